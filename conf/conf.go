@@ -1,9 +1,11 @@
 package conf
 
 import (
+	"context"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -16,77 +18,387 @@ import (
 // Parameters:
 //
 // (optional) configPath. If it is empty, then reading from the file will be skipped.
+// Paths ending in .gz, e.g. conf.yaml.gz, are decompressed with gzip. The path "-" reads
+// YAML from stdin, e.g. piped from `sops -d`.
+// The format is chosen by the extension: .ini files are INI, .properties files are Java
+// properties, .json and .jsonc files are JSON with comments, all other files are YAML.
 //
 // (optional) envPrefix. If it is empty, then "CFG" will be used as the default prefix.
+// A trailing "_" is optional, "MYAPP" and "MYAPP_" both read MYAPP_PG_HOST.
 //
 // Note:
 //
 // The environment variables should be prefixed with `envPrefix`. e.g. `envPrefix` = "CFG",
 // the environment variable should be CFG_PORT. Note that the underline here is used to separate the keys.
 // So the environment variable CFG_PG_HOST will be parsed to the config file as pg.host.
-// You should use `CFG_AuthorizedKey` not `CFG_AUTHORIZED_KEY` if you want to set the value of `authorizedKey`.
-func FetchConfig(configPath string, envPrefix string, cfg any) error {
-	prefix := "CFG"
-	if len(envPrefix) != 0 {
-		prefix = envPrefix
+// The keys are lowercased, see ParseEnv for the exact rules.
+// Fields of inline structs (`yaml:",inline"`) are set by their flat name. Fields of embedded
+// structs are set by their full name, e.g. CFG_BASE_NAME, and also by their flattened name
+// CFG_NAME unless another field has that name.
+//
+// (optional) opts. Options add extra layers on top of the config file and environment variables,
+// e.g. WithFlagSet.
+//
+// String fields tagged `expand:"true"` have a leading "~" and $VARS expanded after decoding,
+// for values that are filesystem paths. Relative paths in fields tagged `relative:"true"` are
+// resolved against the directory of the config file that set them, see PathSource.
+//
+// If cfg implements Validator, it is validated after decoding.
+func FetchConfig(configPath string, envPrefix string, cfg any, opts ...Option) error {
+	return FetchConfigContext(context.Background(), configPath, envPrefix, cfg, opts...)
+}
+
+// FetchConfigContext is like FetchConfig, the context is passed to the tracers registered with WithTracer.
+func FetchConfigContext(ctx context.Context, configPath string, envPrefix string, cfg any, opts ...Option) error {
+	_, err := fetch(ctx, resolvePrefix(envPrefix), configPath, cfg, newOptions(opts))
+	return err
+}
+
+// fetch runs the whole load pipeline once and returns the merged config that was decoded
+// into cfg. The observers are notified about the outcome.
+func fetch(ctx context.Context, prefix, configPath string, cfg any, o *options) (*merged, error) {
+	start := time.Now()
+	ctx, end := o.startStage(ctx, StageLoad, configPath)
+	m, err := fetchConfigMap(ctx, prefix, configPath, cfg, o)
+	end(err)
+	for _, obs := range o.observers {
+		obs.ObserveLoad(time.Since(start), err)
+	}
+	return m, err
+}
+
+func fetchConfigMap(ctx context.Context, prefix, configPath string, cfg any, o *options) (*merged, error) {
+	m, err := readConfigMap(ctx, prefix, configPath, cfg, o)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read and patch config")
+	}
+	for i, transform := range o.transforms {
+		_, end := o.startStage(ctx, StageTransform, strconv.Itoa(i))
+		err := m.transform(transform)
+		end(err)
+		if err != nil {
+			return nil, &layerError{layer: "transform", err: errors.Wrap(err, "failed to transform config")}
+		}
+	}
+	for _, v := range o.treeValidators {
+		_, end := o.startStage(ctx, StageValidate, "tree")
+		err := v.ValidateTree(copyMap(m.config))
+		end(err)
+		if err != nil {
+			return nil, &layerError{layer: "validate", err: errors.Wrap(err, "invalid config")}
+		}
+	}
+	_, end := o.startStage(ctx, StageDecode, "")
+	if o.decoder != nil {
+		err = o.decoder.Decode(copyMap(m.config), cfg)
+	} else {
+		err = decodeConfigMap(m.config, cfg)
+	}
+	end(err)
+	if err != nil {
+		return nil, &layerError{layer: "decode", err: redactError(err, m.config, secretPaths(cfg))}
+	}
+	if err := expandPaths(cfg); err != nil {
+		return nil, &layerError{layer: "decode", err: err}
+	}
+	if err := resolveRelative(cfg, m); err != nil {
+		return nil, &layerError{layer: "decode", err: err}
+	}
+	_, end = o.startStage(ctx, StageValidate, "tags")
+	err = validateTags(cfg, m.config)
+	end(err)
+	if err != nil {
+		// an enum error quotes the value
+		err = redactError(err, m.config, secretPaths(cfg))
+		return nil, &layerError{layer: "validate", err: errors.Wrap(err, "invalid config")}
+	}
+	if v, ok := cfg.(Validator); ok {
+		_, end := o.startStage(ctx, StageValidate, "")
+		err := v.Validate()
+		end(err)
+		if err != nil {
+			return nil, &layerError{layer: "validate", err: errors.Wrap(err, "invalid config")}
+		}
+	}
+	for i, hook := range o.hooks {
+		_, end := o.startStage(ctx, StageHook, strconv.Itoa(i))
+		err := hook(ctx, cfg)
+		end(err)
+		if err != nil {
+			return nil, &layerError{layer: "hook", err: errors.Wrap(err, "post-load hook failed")}
+		}
 	}
-	yamlRaw, err := readConfigFromPathAndEnv(prefix, configPath)
+	logDeprecated(o.log(), m.config, cfg)
+	return m, nil
+}
+
+func decodeConfigMap(config map[string]any, cfg any) error {
+	yamlRaw, err := yaml.Marshal(config)
 	if err != nil {
-		return errors.Wrap(err, "failed to read and patch config")
+		return errors.Wrap(err, "yaml marshal error")
 	}
 	return marshallRawYAML(yamlRaw, cfg)
 }
 
+// Validator is implemented by configs that check themselves after they are loaded. An error
+// fails the load, so a Store keeps its current config.
+type Validator interface {
+	Validate() error
+}
+
+// Decoder decodes the merged config tree into cfg, replacing the default YAML decoding.
+// See the confproto package for protobuf messages.
+type Decoder interface {
+	Decode(config map[string]any, cfg any) error
+}
+
+// WithDecoder makes FetchConfig decode the merged config with d instead of yaml.v3, for
+// destinations that are not plain structs with yaml tags.
+func WithDecoder(d Decoder) Option {
+	return func(o *options) {
+		o.decoder = d
+	}
+}
+
+// WithTransform registers fn to rewrite the merged config before it is validated and decoded,
+// e.g. for custom expansion, key rewriting or feature gating. fn gets a copy of the merged
+// config and returns the config to use instead. Transforms run in the order they were added,
+// an error fails the load.
+func WithTransform(fn func(config map[string]any) (map[string]any, error)) Option {
+	return func(o *options) {
+		o.transforms = append(o.transforms, fn)
+	}
+}
+
+// WithPostLoadHook registers hook to run after the config was decoded and validated, e.g. to
+// normalize URLs or derive computed fields. Hooks run in the order they were added and may
+// modify cfg. An error fails the load, so a Store keeps its current config.
+func WithPostLoadHook(hook func(ctx context.Context, cfg any) error) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hook)
+	}
+}
+
+// TreeValidator checks the merged config tree before it is decoded, e.g. against an external
+// schema. Unlike a Validator it sees exactly which keys are set, including keys the struct
+// does not have.
+type TreeValidator interface {
+	ValidateTree(config map[string]any) error
+}
+
+// TreeValidatorFunc adapts an ordinary function to a TreeValidator.
+type TreeValidatorFunc func(config map[string]any) error
+
+// ValidateTree calls f.
+func (f TreeValidatorFunc) ValidateTree(config map[string]any) error {
+	return f(config)
+}
+
+// WithTreeValidator registers v to check the merged config tree before it is decoded. An
+// error fails the load like an error of Validate. Validators run in the order they were added
+// and get a copy of the tree, so they cannot change the config.
+func WithTreeValidator(v TreeValidator) Option {
+	return func(o *options) {
+		o.treeValidators = append(o.treeValidators, v)
+	}
+}
+
+// resolvePrefix returns envPrefix without a trailing "_", or the default prefix "CFG" if it
+// is empty. Both "MYAPP" and "MYAPP_" read MYAPP_PG_HOST.
+func resolvePrefix(envPrefix string) string {
+	envPrefix = strings.TrimSuffix(envPrefix, "_")
+	if len(envPrefix) != 0 {
+		return envPrefix
+	}
+	return "CFG"
+}
+
 func marshallRawYAML(yamlRaw []byte, cfg any) error {
 	err := yaml.Unmarshal(yamlRaw, cfg)
 	if err != nil {
-		return errors.Wrapf(err, "failed to unmarshal yaml config %v", yamlRaw)
+		return errors.Wrap(err, "failed to unmarshal yaml config")
+	}
+	return nil
+}
+
+// layerError is the failure of a single layer of the pipeline, named like the origins of merged.
+type layerError struct {
+	layer string
+	err   error
+}
+
+func (e *layerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *layerError) Unwrap() error {
+	return e.err
+}
+
+// merged is the config merged from all layers.
+type merged struct {
+	config map[string]any
+	// origins maps the dotted path of every leaf of config to the layer that set it,
+	// e.g. "file:conf.yaml", "env:CFG" or "source:flags".
+	origins map[string]string
+	// dirs maps the layers read from a file to the directory of the file.
+	dirs map[string]string
+	// mergeFuncs merge the values at their dotted path instead of replacing them.
+	mergeFuncs map[string]MergeFunc
+}
+
+func newMerged() *merged {
+	return &merged{config: map[string]any{}, origins: map[string]string{}, dirs: map[string]string{}}
+}
+
+// patch merges patch on top of the config and records layer as the origin of its keys.
+func (m *merged) patch(patch map[string]any, layer string) error {
+	keys := flattenMap(patch)
+	if err := patchConfigMap(patch, m.config, m.mergeFuncs); err != nil {
+		return err
+	}
+	for key := range keys {
+		m.origins[key] = layer
+	}
+	return nil
+}
+
+// transform replaces the config by the result of fn, which gets a copy of it. The keys fn
+// adds or changes get the origin "transform".
+func (m *merged) transform(fn func(config map[string]any) (map[string]any, error)) error {
+	config, err := fn(copyMap(m.config))
+	if err != nil {
+		return err
 	}
+	if config == nil {
+		config = map[string]any{}
+	}
+	config = copyMap(config)
+	flat := flattenMap(config)
+	for _, key := range diffMaps(m.config, config) {
+		if _, ok := flat[key]; ok {
+			m.origins[key] = "transform"
+		}
+	}
+	m.config = config
+	m.prune()
 	return nil
 }
 
-func readConfigFromPathAndEnv(prefix, configPath string) ([]byte, error) {
-	config := map[string]any{}
-	var err error
+// prune drops the origins of keys that were replaced by a higher layer.
+func (m *merged) prune() {
+	keys := flattenMap(m.config)
+	for key := range m.origins {
+		if _, ok := keys[key]; !ok {
+			delete(m.origins, key)
+		}
+	}
+}
+
+// readConfigMap merges all config layers into one map. From the lowest to the highest
+// priority the layers are: the config file, the sources, the environment variables and the overlays.
+// cfg is the struct the config will be decoded into, it is nil if only the map is needed.
+func readConfigMap(ctx context.Context, prefix, configPath string, cfg any, o *options) (*merged, error) {
+	m := newMerged()
+	m.mergeFuncs = o.mergeFuncs
 	if len(configPath) != 0 {
-		config, err = readFromConfigFile(configPath)
+		_, end := o.startStage(ctx, StageFile, configPath)
+		fileConfig, err := readFromConfigFile(configPath, o.yamlLimits(), o.decompressedLimit())
+		end(err)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to read config from %v", configPath)
+			return nil, &layerError{layer: "file:" + configPath, err: errors.Wrapf(err, "failed to read config from %v", configPath)}
+		}
+		if err := m.patch(fileConfig, "file:"+configPath); err != nil {
+			return nil, err
 		}
+		if configPath != stdinPath {
+			m.dirs["file:"+configPath] = fileDir(configPath)
+		}
+		o.log().Info("read config file", "path", configPath)
 	}
 
-	configEnv := readFromConfigEnv(prefix)
+	if err := patchSources(ctx, o, o.sources, m); err != nil {
+		return nil, errors.Wrap(err, "failed to patch config source")
+	}
 
-	if err := patchConfigMap(configEnv, config); err != nil {
-		return nil, errors.Wrap(err, "failed to patch config env to config file")
+	_, end := o.startStage(ctx, StageEnv, prefix)
+	err := patchEnv(prefix, cfg, o, m)
+	end(err)
+	if err != nil {
+		return nil, &layerError{layer: "env:" + prefix, err: err}
 	}
-	yamlRaw, err := yaml.Marshal(config)
+
+	if err := patchSources(ctx, o, o.overlays, m); err != nil {
+		return nil, errors.Wrap(err, "failed to patch config overlay")
+	}
+	m.prune()
+	return m, nil
+}
+
+func patchEnv(prefix string, cfg any, o *options, m *merged) error {
+	configEnv, err := readFromConfigEnv(prefix, cfg)
 	if err != nil {
-		return nil, errors.Wrap(err, "yaml marshal error")
+		return err
 	}
-	return yamlRaw, nil
+	count := countLeaves(configEnv)
+
+	if err := m.patch(configEnv, "env:"+prefix); err != nil {
+		return errors.Wrap(err, "failed to patch config env to config file")
+	}
+
+	if o.envconfig && cfg != nil {
+		configEnvconfig, err := readFromEnvconfigTags(prefix, cfg)
+		if err != nil {
+			return errors.Wrap(err, "failed to read envconfig tagged env")
+		}
+		count += countLeaves(configEnvconfig)
+		if err := m.patch(configEnvconfig, "env:"+prefix); err != nil {
+			return errors.Wrap(err, "failed to patch envconfig tagged env to config file")
+		}
+	}
+	o.log().Info("applied environment variables", "prefix", prefix, "count", count)
+	return nil
+}
+
+func patchSources(ctx context.Context, o *options, sources []Source, m *merged) error {
+	for _, source := range sources {
+		name := sourceName(source)
+		_, end := o.startStage(ctx, StageSource, name)
+		patch, err := source.Load()
+		end(err)
+		if err != nil {
+			return &layerError{layer: "source:" + name, err: errors.Wrapf(err, "failed to load source %s", name)}
+		}
+		if err := m.patch(copyMap(patch), "source:"+name); err != nil {
+			return &layerError{layer: "source:" + name, err: err}
+		}
+		if ps, ok := source.(PathSource); ok {
+			m.dirs["source:"+name] = fileDir(ps.Path())
+		}
+		o.log().Info("loaded config source", "source", name, "keys", countLeaves(patch))
+	}
+	return nil
 }
 
 // patchConfigMap partially validates that both patch and base, then merge patch into base.
-func patchConfigMap(patch, base map[string]any) error {
-	if err := patchMap(base, patch); err != nil {
+func patchConfigMap(patch, base map[string]any, funcs map[string]MergeFunc) error {
+	if err := patchMap(base, patch, "", funcs); err != nil {
 		return errors.Wrap(err, "failed to patch to config file")
 	}
 	return nil
 }
 
-func readFromConfigFile(configPath string) (map[string]any, error) {
-	config := map[string]any{}
-
-	_, err := os.Stat(configPath)
-	if err != nil {
-		return nil, errors.Wrapf(err, "config file %s not found", configPath)
+func readFromConfigFile(configPath string, limits yamlLimits, maxDecompressed int64) (map[string]any, error) {
+	if configPath != stdinPath {
+		if _, err := os.Stat(configPath); err != nil {
+			return nil, errors.Wrapf(err, "config file %s not found", configPath)
+		}
 	}
-	raw, err := os.ReadFile(configPath)
+	raw, err := readFile(configPath, maxDecompressed)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read config file %s", configPath)
 	}
-	err = yaml.Unmarshal(raw, &config)
+	config, err := parseFile(configPath, raw, limits)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal config file %s", configPath)
 	}
@@ -94,51 +406,37 @@ func readFromConfigFile(configPath string) (map[string]any, error) {
 	return config, nil
 }
 
-func readFromConfigEnv(prefix string) map[string]any {
-	envCfg := map[string]any{}
-	for _, v := range os.Environ() {
-		if strings.HasPrefix(v, prefix) {
-			key := strings.Split(v, "=")[0]
-			value := v[len(key)+1:]
-			key = strings.ToLower(strings.Replace(key, prefix, "", 1))
-			parseEnvConfig(envCfg, key, value)
-		}
-	}
-	return envCfg
-}
-
-// parseEnvConfig turn an environment variable to a map
-// by convention, the env key has pattern A_B_C with each yaml config key separated by _
-// calling function with key=MGMT_LOG_LEVEL and value=INFO
-// should update curCfg to {MGMT: {LOG: [LEVEL: INFO}}}.
-func parseEnvConfig(curCfg map[string]any, key string, value string) {
-	i := strings.Index(key, "_")
-	if i == -1 {
-		if intVal, err := strconv.Atoi(value); err == nil {
-			curCfg[key] = intVal
-		} else if boolVal, err := strconv.ParseBool(value); err == nil {
-			curCfg[key] = boolVal
-		} else {
-			curCfg[key] = value
-		}
-	} else {
-		thisKey := key[:i]
-		if _, ok := curCfg[thisKey]; !ok {
-			curCfg[thisKey] = map[string]any{}
+func readFromConfigEnv(prefix string, cfg any) (map[string]any, error) {
+	config := ParseEnv(prefix, os.Environ())
+	if cfg != nil {
+		if err := readSecretFiles(config, cfg); err != nil {
+			return nil, err
 		}
-		parseEnvConfig(curCfg[thisKey].(map[string]any), key[i+1:], value)
+		squashEmbedded(config, cfg)
 	}
+	return config, nil
 }
 
-func patchMap(o map[string]any, p map[string]any) error {
+// patchMap merges p into o. The values at the dotted paths in funcs are merged with the
+// MergeFunc of the path instead of being replaced. prefix is the dotted path of o.
+func patchMap(o map[string]any, p map[string]any, prefix string, funcs map[string]MergeFunc) error {
 	for k := range p {
+		if fn, ok := funcs[prefix+k]; ok && o[k] != nil {
+			merged, err := fn(o[k], p[k])
+			if err != nil {
+				return errors.Wrapf(err, "failed to merge %s", prefix+k)
+			}
+			o[k] = merged
+			continue
+		}
 		if _, ok := o[k]; ok { // if o has the same key
 			if _, ok := o[k].(map[string]any); ok {
 				if _, ok := p[k].(map[string]any); !ok {
-					return errors.Errorf("%s of %s is not a map[string]any", k, p)
+					// only the path is reported, the values may be secrets
+					return errors.Errorf("%s%s is a map in the lower layers and cannot be set to a value", prefix, k)
 				}
 				// o[k] and p[k] are both map
-				if err := patchMap(o[k].(map[string]any), p[k].(map[string]any)); err != nil {
+				if err := patchMap(o[k].(map[string]any), p[k].(map[string]any), prefix+k+".", funcs); err != nil {
 					return err
 				}
 			} else { // both are values
@@ -150,3 +448,16 @@ func patchMap(o map[string]any, p map[string]any) error {
 	}
 	return nil
 }
+
+// setPath sets value at the nested key path of m, creating the intermediate maps if needed.
+func setPath(m map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
@@ -0,0 +1,160 @@
+// Package confhcl reads HCL config files as a conf.Source, so HCL files get the same
+// environment overrides and validation as YAML files.
+//
+// Example:
+//
+//	err := conf.FetchConfig("", "MYAPP", &cfg, conf.WithSource(confhcl.File("conf.hcl")))
+package confhcl
+
+import (
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/cloudcarver/edc/conf"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// File returns a Source reading the HCL file at path. It is a conf.PathSource.
+func File(path string) conf.Source {
+	return source{path: path}
+}
+
+type source struct {
+	path string
+}
+
+func (s source) String() string {
+	return "hcl:" + s.path
+}
+
+// Path returns the path of the file, relative paths in it are resolved against its directory.
+func (s source) Path() string {
+	return s.path
+}
+
+func (s source) Load() (map[string]any, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file %s", s.path)
+	}
+	return Parse(raw, s.path)
+}
+
+// Parse parses HCL source into a nested config map. filename is only used in error messages.
+//
+// Attributes become keys and blocks become nested maps, e.g. `pg { host = "localhost" }` sets
+// pg.host. Block labels add a level each, so `server "web" { port = 80 }` sets server.web.port,
+// and repeated blocks without labels become a list. Expressions are evaluated without
+// variables or functions.
+func Parse(src []byte, filename string) (map[string]any, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, errors.Wrapf(diags, "failed to parse %s", filename)
+	}
+	return bodyMap(file.Body.(*hclsyntax.Body))
+}
+
+func bodyMap(body *hclsyntax.Body) (map[string]any, error) {
+	m := map[string]any{}
+	for name, attr := range body.Attributes {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, errors.Wrapf(diags, "failed to evaluate %s", name)
+		}
+		v, err := goValue(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert %s", name)
+		}
+		m[name] = v
+	}
+	for _, block := range body.Blocks {
+		inner, err := bodyMap(block.Body)
+		if err != nil {
+			return nil, errors.Wrapf(err, "in block %s", block.Type)
+		}
+		if err := addBlock(m, append([]string{block.Type}, block.Labels...), inner); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// addBlock puts the content of a block at the path made of its type and labels. A repeated
+// block turns the value into a list.
+func addBlock(m map[string]any, path []string, block map[string]any) error {
+	for _, key := range path[:len(path)-1] {
+		if _, ok := m[key]; !ok {
+			m[key] = map[string]any{}
+		}
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			return errors.Errorf("block %s conflicts with the attribute %s", strings.Join(path, "."), key)
+		}
+		m = next
+	}
+	last := path[len(path)-1]
+	switch existing := m[last].(type) {
+	case nil:
+		m[last] = block
+	case map[string]any:
+		m[last] = []any{existing, block}
+	case []any:
+		m[last] = append(existing, block)
+	default:
+		return errors.Errorf("block %s conflicts with the attribute %s", strings.Join(path, "."), last)
+	}
+	return nil
+}
+
+// goValue converts an evaluated HCL value into the types conf works with.
+func goValue(v cty.Value) (any, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+	if !v.IsKnown() {
+		return nil, errors.New("value is unknown")
+	}
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return v.AsString(), nil
+	case t == cty.Bool:
+		return v.True(), nil
+	case t == cty.Number:
+		f := v.AsBigFloat()
+		if f.IsInt() {
+			if i, acc := f.Int64(); acc == big.Exact {
+				return int(i), nil
+			}
+		}
+		f64, _ := f.Float64()
+		return f64, nil
+	case t.IsListType() || t.IsSetType() || t.IsTupleType():
+		list := []any{}
+		for it := v.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			item, err := goValue(ev)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case t.IsMapType() || t.IsObjectType():
+		m := map[string]any{}
+		for it := v.ElementIterator(); it.Next(); {
+			k, ev := it.Element()
+			item, err := goValue(ev)
+			if err != nil {
+				return nil, err
+			}
+			m[k.AsString()] = item
+		}
+		return m, nil
+	}
+	return nil, errors.Errorf("unsupported type %s", t.FriendlyName())
+}
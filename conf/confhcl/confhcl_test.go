@@ -0,0 +1,151 @@
+package confhcl
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudcarver/edc/conf"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{
+			name: "attributes",
+			src:  "name = \"api\"\ndebug = true\nnothing = null\n",
+			want: map[string]any{"name": "api", "debug": true, "nothing": nil},
+		},
+		{
+			name: "blocks",
+			src:  "pg {\n  host = \"localhost\"\n}\n",
+			want: map[string]any{"pg": map[string]any{"host": "localhost"}},
+		},
+		{
+			name: "labeled blocks",
+			src:  "server \"web\" {\n  port = 80\n}\nserver \"api\" \"v2\" {\n  port = 8080\n}\n",
+			want: map[string]any{"server": map[string]any{
+				"web": map[string]any{"port": 80},
+				"api": map[string]any{"v2": map[string]any{"port": 8080}},
+			}},
+		},
+		{
+			name: "repeated blocks become a list",
+			src:  "upstream {\n  host = \"a\"\n}\nupstream {\n  host = \"b\"\n}\nupstream {\n  host = \"c\"\n}\n",
+			want: map[string]any{"upstream": []any{
+				map[string]any{"host": "a"},
+				map[string]any{"host": "b"},
+				map[string]any{"host": "c"},
+			}},
+		},
+		{
+			name: "repeated labeled blocks become a list",
+			src:  "server \"web\" {\n  port = 80\n}\nserver \"web\" {\n  port = 81\n}\n",
+			want: map[string]any{"server": map[string]any{"web": []any{
+				map[string]any{"port": 80},
+				map[string]any{"port": 81},
+			}}},
+		},
+		{
+			name: "numbers",
+			src:  "port = 5432\nratio = 0.5\nnegative = -3\nexp = 1e3\nhuge = 1e30\n",
+			want: map[string]any{"port": 5432, "ratio": 0.5, "negative": -3, "exp": 1000, "huge": 1e30},
+		},
+		{
+			name: "collections",
+			src:  "hosts = [\"a\", \"b\"]\nlimits = { cpu = 2, memory = \"1Gi\" }\n",
+			want: map[string]any{
+				"hosts":  []any{"a", "b"},
+				"limits": map[string]any{"cpu": 2, "memory": "1Gi"},
+			},
+		},
+		{
+			name: "expressions without variables",
+			src:  "timeout = \"${10 * 3}s\"\nworkers = 2 * 4\n",
+			want: map[string]any{"timeout": "30s", "workers": 8},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.src), "conf.hcl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "syntax",
+			src:  "pg {\n",
+			want: "failed to parse conf.hcl",
+		},
+		{
+			name: "variables",
+			src:  "host = var.host\n",
+			want: "failed to evaluate host",
+		},
+		{
+			name: "block conflicts with an attribute",
+			src:  "pg = \"x\"\npg {\n  host = \"localhost\"\n}\n",
+			want: "block pg conflicts with the attribute pg",
+		},
+		{
+			name: "labeled block conflicts with an attribute",
+			src:  "server = 1\nserver \"web\" {\n  port = 80\n}\n",
+			want: "block server.web conflicts with the attribute server",
+		},
+		{
+			name: "nested block conflicts with an attribute",
+			src:  "pg {\n  tls = true\n  tls {\n    cert = \"c\"\n  }\n}\n",
+			want: "in block pg: block tls conflicts with the attribute tls",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src), "conf.hcl")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Parse() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.hcl")
+	if err := os.WriteFile(path, []byte("pg {\n  host = \"localhost\"\n  port = 5432\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HCLTEST_PG_PORT", "5433")
+	cfg := struct {
+		PG struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+		} `yaml:"pg"`
+	}{}
+	if err := conf.FetchConfig("", "HCLTEST", &cfg, conf.WithSource(File(path))); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PG.Host != "localhost" || cfg.PG.Port != 5433 {
+		t.Errorf("config = %+v, want localhost:5433", cfg.PG)
+	}
+
+	_, err := File(filepath.Join(t.TempDir(), "missing.hcl")).Load()
+	if err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("Load() of a missing file = %v, want a read error", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package confotel traces the config load pipeline with OpenTelemetry, so slow startups
+// caused by config backends show up in traces.
+//
+// Example:
+//
+//	err := conf.FetchConfigContext(ctx, "conf.yaml", "MYAPP", &cfg, conf.WithTracer(confotel.NewTracer(nil)))
+package confotel
+
+import (
+	"context"
+
+	"github.com/cloudcarver/edc/conf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/cloudcarver/edc/conf/confotel"
+
+// Tracer implements conf.Tracer. Every stage of the pipeline becomes a span named after it,
+// e.g. conf.file, and a reload records a conf.reload event with its outcome.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ conf.Tracer = (*Tracer)(nil)
+
+// NewTracer creates a Tracer using tp. If tp is nil, the global TracerProvider is used.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// StartStage implements conf.Tracer.
+func (t *Tracer) StartStage(ctx context.Context, stage conf.Stage, name string) (context.Context, func(error)) {
+	opts := []trace.SpanStartOption{}
+	if len(name) != 0 {
+		opts = append(opts, trace.WithAttributes(attribute.String("conf.name", name)))
+	}
+	ctx, span := t.tracer.Start(ctx, "conf."+string(stage), opts...)
+	return ctx, func(err error) {
+		if stage == conf.StageReload {
+			span.AddEvent("conf.reload", trace.WithAttributes(attribute.Bool("conf.reload.success", err == nil)))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
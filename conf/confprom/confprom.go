@@ -0,0 +1,102 @@
+// Package confprom exports Prometheus metrics about config loading and reloading.
+//
+// Example:
+//
+//	metrics := confprom.NewMetrics("myapp")
+//	prometheus.MustRegister(metrics)
+//	store, err := conf.NewStore("conf.yaml", "MYAPP", &cfg, conf.WithObserver(metrics))
+package confprom
+
+import (
+	"time"
+
+	"github.com/cloudcarver/edc/conf"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a prometheus.Collector and a conf.Observer. Register it on any
+// prometheus.Registerer and pass it to conf with conf.WithObserver.
+type Metrics struct {
+	loadDuration      prometheus.Histogram
+	loadFailures      prometheus.Counter
+	reloads           prometheus.Counter
+	reloadFailures    prometheus.Counter
+	lastReloadSuccess prometheus.Gauge
+}
+
+var _ conf.Observer = (*Metrics)(nil)
+var _ prometheus.Collector = (*Metrics)(nil)
+
+// NewMetrics creates the config metrics. The metric names are prefixed with namespace,
+// e.g. myapp_config_reloads_total.
+func NewMetrics(namespace string) *Metrics {
+	return &Metrics{
+		loadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "config",
+			Name:      "load_duration_seconds",
+			Help:      "Duration of loading the config, including reloads.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}),
+		loadFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "config",
+			Name:      "load_failures_total",
+			Help:      "Number of failed config loads, including reloads.",
+		}),
+		reloads: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "config",
+			Name:      "reloads_total",
+			Help:      "Number of attempted config reloads.",
+		}),
+		reloadFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "config",
+			Name:      "reload_failures_total",
+			Help:      "Number of failed config reloads.",
+		}),
+		lastReloadSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "config",
+			Name:      "last_reload_success_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful config load or reload.",
+		}),
+	}
+}
+
+// ObserveLoad implements conf.Observer.
+func (m *Metrics) ObserveLoad(d time.Duration, err error) {
+	m.loadDuration.Observe(d.Seconds())
+	if err != nil {
+		m.loadFailures.Inc()
+		return
+	}
+	m.lastReloadSuccess.SetToCurrentTime()
+}
+
+// ObserveReload implements conf.Observer.
+func (m *Metrics) ObserveReload(err error) {
+	m.reloads.Inc()
+	if err != nil {
+		m.reloadFailures.Inc()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.loadDuration, m.loadFailures, m.reloads, m.reloadFailures, m.lastReloadSuccess}
+}
@@ -0,0 +1,196 @@
+// Package confproto decodes config into protobuf messages, for services whose canonical config
+// type is a protobuf.
+//
+// Example:
+//
+//	cfg := &pb.Config{}
+//	err := conf.FetchConfig("conf.yaml", "MYAPP", cfg, confproto.WithProto())
+package confproto
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudcarver/edc/conf"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// WithProto makes conf decode the config into a proto.Message with the semantics of protojson,
+// so keys may use the JSON or the proto name of a field, enums may be names or numbers, and
+// well-known types like google.protobuf.Duration use their JSON form, e.g. "30s". Unknown
+// keys are an error.
+//
+// Values are converted to the type of their field first, since environment variables and
+// flat file formats produce ints and bools where the message may expect strings. Keys that
+// match no field exactly are matched case-insensitively, since environment variable names are
+// lowercased.
+func WithProto() conf.Option {
+	return conf.WithDecoder(Decoder{})
+}
+
+// Decoder is a conf.Decoder for proto.Message destinations.
+type Decoder struct {
+	// Options are the options to unmarshal with, e.g. DiscardUnknown.
+	Options protojson.UnmarshalOptions
+}
+
+var _ conf.Decoder = Decoder{}
+
+// Decode decodes config into cfg, which must be a proto.Message.
+func (d Decoder) Decode(config map[string]any, cfg any) error {
+	msg, ok := cfg.(proto.Message)
+	if !ok {
+		return errors.Errorf("cfg must be a proto.Message, got %T", cfg)
+	}
+	config = coerceMessage(config, msg.ProtoReflect().Descriptor())
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal config to json")
+	}
+	if err := d.Options.Unmarshal(raw, msg); err != nil {
+		return errors.Wrap(err, "failed to unmarshal config into proto message")
+	}
+	return nil
+}
+
+// coerceMessage returns m with the keys renamed to the JSON names of the fields of md and the
+// values converted to what protojson expects for them.
+//
+// Keys matching a field case-insensitively come from environment variables, they are merged
+// on top of the keys matching it exactly.
+func coerceMessage(m map[string]any, md protoreflect.MessageDescriptor) map[string]any {
+	fields := md.Fields()
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return exactField(fields, keys[i]) != nil && exactField(fields, keys[j]) == nil
+	})
+	out := make(map[string]any, len(m))
+	for _, key := range keys {
+		fd := lookupField(fields, key)
+		if fd == nil {
+			// left to protojson to report
+			out[key] = m[key]
+			continue
+		}
+		name := fd.JSONName()
+		out[name] = mergeValue(out[name], coerceField(m[key], fd))
+	}
+	return out
+}
+
+func exactField(fields protoreflect.FieldDescriptors, key string) protoreflect.FieldDescriptor {
+	if fd := fields.ByJSONName(key); fd != nil {
+		return fd
+	}
+	return fields.ByTextName(key)
+}
+
+func lookupField(fields protoreflect.FieldDescriptors, key string) protoreflect.FieldDescriptor {
+	if fd := exactField(fields, key); fd != nil {
+		return fd
+	}
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if strings.EqualFold(fd.JSONName(), key) || strings.EqualFold(string(fd.Name()), key) {
+			return fd
+		}
+	}
+	return nil
+}
+
+// mergeValue merges patch on top of base, maps are merged key by key.
+func mergeValue(base, patch any) any {
+	b, ok := base.(map[string]any)
+	p, ok2 := patch.(map[string]any)
+	if !ok || !ok2 {
+		return patch
+	}
+	for k, v := range p {
+		b[k] = mergeValue(b[k], v)
+	}
+	return b
+}
+
+func coerceField(value any, fd protoreflect.FieldDescriptor) any {
+	switch {
+	case fd.IsMap():
+		if m, ok := value.(map[string]any); ok {
+			for k, v := range m {
+				m[k] = coerceSingular(v, fd.MapValue())
+			}
+		}
+		return value
+	case fd.IsList():
+		if list, ok := value.([]any); ok {
+			for i, v := range list {
+				list[i] = coerceSingular(v, fd)
+			}
+		}
+		return value
+	}
+	return coerceSingular(value, fd)
+}
+
+// coerceSingular converts a single value of the field fd, lists and maps are handled by
+// coerceField.
+func coerceSingular(value any, fd protoreflect.FieldDescriptor) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		md := fd.Message()
+		if wrappers[md.FullName()] {
+			// wrappers use the JSON form of their value field
+			return coerceSingular(value, md.Fields().ByName("value"))
+		}
+		if m, ok := value.(map[string]any); ok && !specialJSON[md.FullName()] {
+			return coerceMessage(m, md)
+		}
+	case protoreflect.StringKind:
+		switch value.(type) {
+		case string, nil:
+		default:
+			return fmt.Sprint(value)
+		}
+	case protoreflect.BoolKind:
+		if s, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+// specialJSON are the well-known types with a special JSON form, their fields are not keys of
+// the config. The wrappers are handled separately.
+var specialJSON = map[protoreflect.FullName]bool{
+	"google.protobuf.Any":       true,
+	"google.protobuf.Duration":  true,
+	"google.protobuf.Timestamp": true,
+	"google.protobuf.FieldMask": true,
+	"google.protobuf.Struct":    true,
+	"google.protobuf.Value":     true,
+	"google.protobuf.ListValue": true,
+	"google.protobuf.Empty":     true,
+}
+
+// wrappers are the well-known wrapper types, their JSON form is the one of their value field.
+var wrappers = map[protoreflect.FullName]bool{
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.BytesValue":  true,
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.UInt64Value": true,
+}
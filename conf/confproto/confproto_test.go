@@ -0,0 +1,158 @@
+package confproto
+
+import (
+	"testing"
+
+	"github.com/cloudcarver/edc/conf"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// wellKnownMessage returns a message with fields of well-known types, built at runtime since
+// the module has no generated test protos.
+func wellKnownMessage(t *testing.T) proto.Message {
+	t.Helper()
+	field := func(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(typeName),
+		}
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("confproto_test.proto"),
+		Package:    proto.String("confproto.test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/duration.proto", "google/protobuf/wrappers.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Config"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("timeout", 1, ".google.protobuf.Duration"),
+				field("max_conns", 2, ".google.protobuf.Int64Value"),
+				field("label", 3, ".google.protobuf.StringValue"),
+				field("debug", 4, ".google.protobuf.BoolValue"),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dynamicpb.NewMessage(fd.Messages().ByName("Config"))
+}
+
+func TestDecode(t *testing.T) {
+	fileDescriptor := func(*testing.T) proto.Message { return &descriptorpb.FileDescriptorProto{} }
+	tests := []struct {
+		name   string
+		newMsg func(t *testing.T) proto.Message
+		config map[string]any
+		want   string
+	}{
+		{
+			name:   "json and proto names",
+			newMsg: fileDescriptor,
+			config: map[string]any{"name": "a.proto", "publicDependency": []any{1}, "weak_dependency": []any{2}},
+			want:   `{"name": "a.proto", "publicDependency": [1], "weakDependency": [2]}`,
+		},
+		{
+			name:   "case-folded keys",
+			newMsg: fileDescriptor,
+			config: map[string]any{"options": map[string]any{"javapackage": "com.example", "go_package": "example"}},
+			want:   `{"options": {"javaPackage": "com.example", "goPackage": "example"}}`,
+		},
+		{
+			name:   "case-folded keys are merged on top of exact keys",
+			newMsg: fileDescriptor,
+			config: map[string]any{
+				"options": map[string]any{"javaPackage": "exact", "goPackage": "exact"},
+				"OPTIONS": map[string]any{"javapackage": "folded"},
+			},
+			want: `{"options": {"javaPackage": "folded", "goPackage": "exact"}}`,
+		},
+		{
+			name:   "ints to strings",
+			newMsg: fileDescriptor,
+			config: map[string]any{"name": 42, "dependency": []any{1, "b.proto"}},
+			want:   `{"name": "42", "dependency": ["1", "b.proto"]}`,
+		},
+		{
+			name:   "strings to bools",
+			newMsg: fileDescriptor,
+			config: map[string]any{"options": map[string]any{"java_multiple_files": "true", "deprecated": "false"}},
+			want:   `{"options": {"javaMultipleFiles": true, "deprecated": false}}`,
+		},
+		{
+			name:   "enum names",
+			newMsg: fileDescriptor,
+			config: map[string]any{"options": map[string]any{"optimize_for": "CODE_SIZE"}},
+			want:   `{"options": {"optimizeFor": "CODE_SIZE"}}`,
+		},
+		{
+			name:   "nested messages in lists",
+			newMsg: fileDescriptor,
+			config: map[string]any{"message_type": []any{map[string]any{"name": 7, "FIELD": []any{map[string]any{"name": "id", "number": 1}}}}},
+			want:   `{"messageType": [{"name": "7", "field": [{"name": "id", "number": 1}]}]}`,
+		},
+		{
+			name:   "well-known types",
+			newMsg: wellKnownMessage,
+			config: map[string]any{"timeout": "90.5s", "max_conns": 5, "label": 42, "debug": "true"},
+			want:   `{"timeout": "90.500s", "maxConns": "5", "label": "42", "debug": true}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.newMsg(t)
+			if err := (Decoder{}).Decode(tt.config, got); err != nil {
+				t.Fatal(err)
+			}
+			want := got.ProtoReflect().New().Interface()
+			if err := protojson.Unmarshal([]byte(tt.want), want); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, want) {
+				t.Errorf("Decode() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if err := (Decoder{}).Decode(map[string]any{}, &struct{}{}); err == nil {
+		t.Error("Decode() into a struct succeeded, want an error")
+	}
+	if err := (Decoder{}).Decode(map[string]any{"nmae": "a.proto"}, &descriptorpb.FileDescriptorProto{}); err == nil {
+		t.Error("Decode() of an unknown key succeeded, want an error")
+	}
+	d := Decoder{Options: protojson.UnmarshalOptions{DiscardUnknown: true}}
+	if err := d.Decode(map[string]any{"nmae": "a.proto"}, &descriptorpb.FileDescriptorProto{}); err != nil {
+		t.Errorf("Decode() with DiscardUnknown = %v, want nil", err)
+	}
+}
+
+func TestWithProto(t *testing.T) {
+	t.Setenv("PROTOTEST_NAME", "42")
+	t.Setenv("PROTOTEST_OPTIONS_JAVAPACKAGE", "com.example")
+	t.Setenv("PROTOTEST_OPTIONS_DEPRECATED", "true")
+	got := &descriptorpb.FileDescriptorProto{}
+	source := conf.Static(map[string]any{"package": "example", "options": map[string]any{"javaPackage": "file"}})
+	if err := conf.FetchConfig("", "PROTOTEST", got, WithProto(), conf.WithSource(source)); err != nil {
+		t.Fatal(err)
+	}
+	want := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("42"),
+		Package: proto.String("example"),
+		Options: &descriptorpb.FileOptions{JavaPackage: proto.String("com.example"), Deprecated: proto.Bool(true)},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("FetchConfig() = %v, want %v", got, want)
+	}
+}
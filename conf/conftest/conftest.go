@@ -0,0 +1,91 @@
+// Package conftest provides helpers for testing code that loads its config with conf.
+//
+// Example:
+//
+//	func TestConfig(t *testing.T) {
+//		path := conftest.WriteFile(t, "conf.yaml", "pg:\n  host: localhost\n")
+//		conftest.Setenv(t, "MYAPP", map[string]string{"pg.port": "5433"})
+//
+//		got := Config{}
+//		conftest.Load(t, path, "MYAPP", &got)
+//		conftest.Equal(t, Config{PG: PG{Host: "localhost", Port: 5433}}, got)
+//	}
+package conftest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudcarver/edc/conf"
+	"gopkg.in/yaml.v3"
+)
+
+// WriteFile writes content to a file called name in a temporary directory that is removed
+// when the test ends, and returns its path.
+func WriteFile(t testing.TB, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file %s: %v", path, err)
+	}
+	return path
+}
+
+// WriteYAML marshals v as YAML into a temporary config file and returns its path.
+func WriteYAML(t testing.TB, v any) string {
+	t.Helper()
+	raw, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	return WriteFile(t, "conf.yaml", string(raw))
+}
+
+// EnvName returns the environment variable conf reads the dotted path from, e.g.
+// EnvName("CFG", "pg.host") returns CFG_PG_HOST. Like conf, it accepts the prefix with a
+// trailing "_".
+func EnvName(prefix string, path string) string {
+	prefix = strings.TrimSuffix(prefix, "_")
+	if len(prefix) == 0 {
+		prefix = "CFG"
+	}
+	return prefix + "_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// Setenv sets the environment variable of every dotted path in vars using t.Setenv, so the
+// previous values are restored when the test ends. Like t.Setenv it cannot be used in
+// parallel tests.
+func Setenv(t testing.TB, prefix string, vars map[string]string) {
+	t.Helper()
+	for path, value := range vars {
+		t.Setenv(EnvName(prefix, path), value)
+	}
+}
+
+// Load calls conf.FetchConfig and fails the test if it returns an error.
+func Load(t testing.TB, configPath string, envPrefix string, cfg any, opts ...conf.Option) {
+	t.Helper()
+	if err := conf.FetchConfig(configPath, envPrefix, cfg, opts...); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+}
+
+// Equal fails the test if want and got are not deeply equal, showing both as YAML.
+func Equal(t testing.TB, want any, got any) {
+	t.Helper()
+	if reflect.DeepEqual(want, got) {
+		return
+	}
+	t.Errorf("config mismatch\n--- want\n%s--- got\n%s", render(want), render(got))
+}
+
+func render(v any) string {
+	raw, err := yaml.Marshal(v)
+	if err != nil {
+		return "<" + err.Error() + ">\n"
+	}
+	return string(raw)
+}
@@ -0,0 +1,114 @@
+package conftest
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	PG struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	} `yaml:"pg"`
+}
+
+// recorder is a testing.TB recording failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failed = true
+	r.msg += fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// record runs fn with a recorder in its own goroutine, so that Fatalf can stop it.
+func record(t *testing.T, fn func(tb testing.TB)) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		prefix string
+		path   string
+		want   string
+	}{
+		{prefix: "MYAPP", path: "pg.host", want: "MYAPP_PG_HOST"},
+		{prefix: "MYAPP_", path: "pg.host", want: "MYAPP_PG_HOST"},
+		{prefix: "", path: "pg.host", want: "CFG_PG_HOST"},
+		{prefix: "_", path: "debug", want: "CFG_DEBUG"},
+	}
+	for _, tt := range tests {
+		if got := EnvName(tt.prefix, tt.path); got != tt.want {
+			t.Errorf("EnvName(%q, %q) = %q, want %q", tt.prefix, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSetenvLoad(t *testing.T) {
+	for _, prefix := range []string{"CONFTEST", "CONFTEST_"} {
+		t.Run(prefix, func(t *testing.T) {
+			path := WriteFile(t, "conf.yaml", "pg:\n  host: localhost\n  port: 5432\n")
+			Setenv(t, prefix, map[string]string{"pg.port": "5433"})
+			if got := os.Getenv("CONFTEST_PG_PORT"); got != "5433" {
+				t.Fatalf("CONFTEST_PG_PORT = %q, want 5433", got)
+			}
+
+			got := testConfig{}
+			Load(t, path, prefix, &got)
+			want := testConfig{}
+			want.PG.Host = "localhost"
+			want.PG.Port = 5433
+			Equal(t, want, got)
+		})
+	}
+}
+
+func TestLoadFails(t *testing.T) {
+	path := WriteFile(t, "conf.yaml", "pg:\n  port: abc\n")
+	r := record(t, func(tb testing.TB) {
+		Load(tb, path, "CONFTEST", &testConfig{})
+	})
+	if !r.failed || !strings.Contains(r.msg, "failed to load config") {
+		t.Errorf("Load() of an invalid config did not fail the test: %q", r.msg)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := testConfig{}
+	a.PG.Host = "localhost"
+	b := a
+	if r := record(t, func(tb testing.TB) { Equal(tb, a, b) }); r.failed {
+		t.Errorf("Equal() of equal configs failed: %s", r.msg)
+	}
+
+	b.PG.Host = "db"
+	r := record(t, func(tb testing.TB) { Equal(tb, a, b) })
+	if !r.failed {
+		t.Fatal("Equal() of different configs did not fail")
+	}
+	for _, want := range []string{"--- want\npg:\n    host: localhost\n", "--- got\npg:\n    host: db\n"} {
+		if !strings.Contains(r.msg, want) {
+			t.Errorf("Equal() message does not contain %q:\n%s", want, r.msg)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package conftest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cloudcarver/edc/conf"
+	"gopkg.in/yaml.v3"
+)
+
+// Update makes Golden rewrite the golden files instead of comparing them. Golden also
+// rewrites them if the environment variable UPDATE_GOLDEN is set to a true value, or if the
+// test binary defines a boolean -update flag, as in the common
+//
+//	var update = flag.Bool("update", false, "update the golden files")
+//
+// and it is set. conftest registers no flag itself, so it never conflicts with one defined
+// by the test package.
+var Update bool
+
+func updateGolden() bool {
+	if Update {
+		return true
+	}
+	if update, err := strconv.ParseBool(os.Getenv("UPDATE_GOLDEN")); err == nil && update {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	update, _ := getter.Get().(bool)
+	return update
+}
+
+// Golden loads the config like Load and compares the effective config, rendered as YAML with
+// sorted keys and secret fields redacted, to the golden file. Set Update or UPDATE_GOLDEN=1,
+// or run the test with -update if the package defines that flag, to rewrite the golden file,
+// so config changes show up as diffs in code review.
+func Golden(t testing.TB, golden string, configPath string, envPrefix string, cfg any, opts ...conf.Option) {
+	t.Helper()
+	Load(t, configPath, envPrefix, cfg, opts...)
+	config, err := conf.Redacted(cfg)
+	if err != nil {
+		t.Fatalf("failed to redact config: %v", err)
+	}
+	got, err := yaml.Marshal(config)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatalf("failed to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("failed to update golden file %s: %v", golden, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s, set UPDATE_GOLDEN=1 to create it: %v", golden, err)
+	}
+	if string(want) != string(got) {
+		t.Errorf("effective config does not match %s, set UPDATE_GOLDEN=1 if the change is intended\n--- want\n%s--- got\n%s", golden, want, got)
+	}
+}
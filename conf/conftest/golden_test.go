@@ -0,0 +1,42 @@
+package conftest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update is the common golden file idiom, it must not conflict with conftest.
+var update = flag.Bool("update", false, "update the golden files")
+
+type goldenConfig struct {
+	Host     string `yaml:"host"`
+	Password string `yaml:"password" secret:"true"`
+}
+
+func TestGolden(t *testing.T) {
+	if *update {
+		t.Skip("the test rewrites its own golden files")
+	}
+	path := WriteFile(t, "conf.yaml", "host: localhost\npassword: hunter2\n")
+	golden := filepath.Join(t.TempDir(), "testdata", "config.golden.yaml")
+
+	Update = true
+	Golden(t, golden, path, "GOLDENTEST", &goldenConfig{})
+	Update = false
+	raw, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "host: localhost\npassword: <redacted>\n"; string(raw) != want {
+		t.Fatalf("golden file = %q, want %q", raw, want)
+	}
+
+	Golden(t, golden, path, "GOLDENTEST", &goldenConfig{})
+
+	t.Setenv("UPDATE_GOLDEN", "1")
+	if !updateGolden() {
+		t.Error("updateGolden() = false with UPDATE_GOLDEN=1")
+	}
+}
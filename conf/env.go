@@ -0,0 +1,165 @@
+package conf
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseEnv parses environment entries in the "NAME=value" form of os.Environ into a nested
+// config map, the same way FetchConfig reads the environment. It is exported so that other
+// tools can map environment variables with exactly the same semantics.
+//
+// The rules are:
+//
+//   - Only names starting with prefix followed by "_" are used, e.g. CFG_PG_HOST for the
+//     prefix CFG. A prefix ending in "_" is the same as one without it, so the prefix CFG_
+//     also reads CFG_PG_HOST. The rest of the name is split on "_" into the key path and
+//     lowercased, so CFG_PG_HOST sets pg.host. See EnvPath.
+//   - The value is everything after the first "=", so values may contain "=". Entries
+//     without "=" are ignored.
+//   - Names with empty segments, i.e. consecutive, leading or trailing underscores like
+//     CFG_PG__HOST or CFG_PG_, and names that are not valid UTF-8 are ignored. Other
+//     unicode characters are allowed and lowercased.
+//   - Values are converted to an int or a bool if possible, otherwise kept as strings.
+//   - If a name is a prefix of another one, e.g. CFG_PG=x and CFG_PG_HOST=y, the longer
+//     one wins and the shorter one is ignored, regardless of the order of environ. If
+//     several names map to the same path, e.g. CFG_PG_HOST and CFG_pg_host, the first one
+//     in environ wins.
+func ParseEnv(prefix string, environ []string) map[string]any {
+	type entry struct {
+		path  []string
+		value string
+	}
+	entries := []entry{}
+	for _, v := range environ {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok {
+			continue
+		}
+		path, ok := EnvPath(prefix, name)
+		if !ok {
+			continue
+		}
+		entries = append(entries, entry{path: path, value: value})
+	}
+	// longer paths first, so that a nested key always wins over a value at its parent
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].path) > len(entries[j].path)
+	})
+
+	envCfg := map[string]any{}
+	for _, e := range entries {
+		setEnvPath(envCfg, e.path, parseValue(e.value))
+	}
+	return envCfg
+}
+
+// EnvPath returns the key path an environment variable name maps to, e.g. ["pg", "host"] for
+// CFG_PG_HOST with the prefix CFG. ok is false if the name is not used, see ParseEnv.
+func EnvPath(prefix string, name string) (path []string, ok bool) {
+	prefix = strings.TrimSuffix(prefix, "_")
+	if !utf8.ValidString(name) || !strings.HasPrefix(name, prefix+"_") {
+		return nil, false
+	}
+	path = strings.Split(strings.ToLower(name[len(prefix)+1:]), "_")
+	for _, segment := range path {
+		if len(segment) == 0 {
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+// setEnvPath sets value at path unless a map already exists there or a value exists at one
+// of its parents.
+func setEnvPath(m map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		if _, ok := m[key]; !ok {
+			m[key] = map[string]any{}
+		}
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			return
+		}
+		m = next
+	}
+	last := path[len(path)-1]
+	if _, ok := m[last]; ok {
+		return
+	}
+	m[last] = value
+}
+
+// parseValue converts a raw string value to an int or a bool if possible, otherwise the
+// value is kept as a string.
+func parseValue(value string) any {
+	if intVal, err := strconv.Atoi(value); err == nil {
+		return intVal
+	} else if boolVal, err := strconv.ParseBool(value); err == nil {
+		return boolVal
+	}
+	return value
+}
+
+// squashEmbedded moves the values set by the flattened names of fields in embedded structs,
+// e.g. CFG_NAME for the field Name of an embedded struct Base, to the yaml path of the field,
+// base.name. Names of other fields take precedence, and so does the full name CFG_BASE_NAME
+// if both are set. Fields of inline structs need no moving, their yaml path is flat already.
+func squashEmbedded(config map[string]any, cfg any) {
+	fields, err := leafFields(cfg)
+	if err != nil {
+		return
+	}
+	paths := map[string]bool{}
+	for _, f := range fields {
+		paths[strings.ToLower(strings.Join(f.path, "."))] = true
+	}
+	for _, f := range fields {
+		key := strings.ToLower(strings.Join(f.envPath, "."))
+		if paths[key] {
+			continue
+		}
+		envPath := strings.Split(key, ".")
+		value, ok := getPath(config, envPath)
+		if !ok {
+			continue
+		}
+		deletePath(config, envPath)
+		if _, ok := getPath(config, f.path); !ok {
+			setPath(config, f.path, value)
+		}
+	}
+}
+
+// getPath returns the value at path in m.
+func getPath(m map[string]any, path []string) (any, bool) {
+	var cur any = m
+	for _, key := range path {
+		next, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = next[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// deletePath removes the value at path from m, and the maps left empty by it.
+func deletePath(m map[string]any, path []string) {
+	if len(path) == 1 {
+		delete(m, path[0])
+		return
+	}
+	next, ok := m[path[0]].(map[string]any)
+	if !ok {
+		return
+	}
+	deletePath(next, path[1:])
+	if len(next) == 0 {
+		delete(m, path[0])
+	}
+}
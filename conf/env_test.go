@@ -0,0 +1,152 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    map[string]any
+	}{
+		{
+			name:    "nested keys",
+			environ: []string{"CFG_PG_HOST=localhost", "CFG_PG_PORT=5432", "CFG_DEBUG=true"},
+			want:    map[string]any{"pg": map[string]any{"host": "localhost", "port": 5432}, "debug": true},
+		},
+		{
+			name:    "value containing =",
+			environ: []string{"CFG_DSN=user=admin password=x"},
+			want:    map[string]any{"dsn": "user=admin password=x"},
+		},
+		{
+			name:    "empty value",
+			environ: []string{"CFG_HOST="},
+			want:    map[string]any{"host": ""},
+		},
+		{
+			name:    "other prefixes are ignored",
+			environ: []string{"CFGX_HOST=a", "CFG=b", "OTHER_CFG_HOST=c", "CFG_"},
+			want:    map[string]any{},
+		},
+		{
+			name:    "empty segments are ignored",
+			environ: []string{"CFG_PG__HOST=a", "CFG_PG_=b", "CFG__PG=c", "CFG_=d"},
+			want:    map[string]any{},
+		},
+		{
+			name:    "unicode is lowercased",
+			environ: []string{"CFG_ÜBER_NAME=ä"},
+			want:    map[string]any{"über": map[string]any{"name": "ä"}},
+		},
+		{
+			name:    "invalid utf-8 is ignored",
+			environ: []string{"CFG_\xff=a"},
+			want:    map[string]any{},
+		},
+		{
+			name:    "nested key wins over its parent",
+			environ: []string{"CFG_PG=a", "CFG_PG_HOST=b"},
+			want:    map[string]any{"pg": map[string]any{"host": "b"}},
+		},
+		{
+			name:    "nested key wins over its parent in any order",
+			environ: []string{"CFG_PG_HOST=b", "CFG_PG=a"},
+			want:    map[string]any{"pg": map[string]any{"host": "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseEnv("CFG", tt.environ)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvPrefix(t *testing.T) {
+	environ := []string{"MYAPP_PG_HOST=localhost", "MYAPP__PG_PORT=1"}
+	want := map[string]any{"pg": map[string]any{"host": "localhost"}}
+	for _, prefix := range []string{"MYAPP", "MYAPP_"} {
+		t.Run(prefix, func(t *testing.T) {
+			if got := ParseEnv(prefix, environ); !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseEnv() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFetchConfigPrefix(t *testing.T) {
+	t.Setenv("CFG_PG_HOST", "localhost")
+	for _, prefix := range []string{"", "CFG", "CFG_"} {
+		t.Run(prefix, func(t *testing.T) {
+			cfg := struct {
+				PG struct {
+					Host string `yaml:"host"`
+				} `yaml:"pg"`
+			}{}
+			if err := FetchConfig("", prefix, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.PG.Host != "localhost" {
+				t.Errorf("pg.host = %q, want %q", cfg.PG.Host, "localhost")
+			}
+		})
+	}
+}
+
+func FuzzParseEnv(f *testing.F) {
+	f.Add("CFG_PG_HOST", "localhost")
+	f.Add("CFG_PG__HOST", "a=b")
+	f.Add("CFG_PG_", "1")
+	f.Add("CFG_ÜBER", "true")
+	f.Add("CFG", "")
+	f.Add("CFG_\xff", "x")
+	f.Fuzz(func(t *testing.T, name string, value string) {
+		if strings.Contains(name, "=") {
+			// the name ends at the first =, the rest belongs to the value
+			return
+		}
+		config := ParseEnv("CFG", []string{name + "=" + value})
+		path, ok := EnvPath("CFG", name)
+		if !ok {
+			if len(config) != 0 {
+				t.Fatalf("ignored name %q produced %v", name, config)
+			}
+			return
+		}
+		var cur any = config
+		for _, key := range path {
+			m, isMap := cur.(map[string]any)
+			if !isMap {
+				t.Fatalf("path %v of %q not found in %v", path, name, config)
+			}
+			cur = m[key]
+		}
+		if want := parseValue(value); !reflect.DeepEqual(cur, want) {
+			t.Fatalf("value of %q = %#v, want %#v", name, cur, want)
+		}
+	})
+}
+
+func FuzzParseEnvOrder(f *testing.F) {
+	f.Add("CFG_PG", "CFG_PG_HOST")
+	f.Add("CFG_A_B_C", "CFG_A")
+	f.Fuzz(func(t *testing.T, a string, b string) {
+		pa, okA := EnvPath("CFG", a)
+		pb, okB := EnvPath("CFG", b)
+		if okA && okB && reflect.DeepEqual(pa, pb) {
+			// the first of two names with the same path wins by definition
+			return
+		}
+		got := ParseEnv("CFG", []string{a + "=1", b + "=2"})
+		reversed := ParseEnv("CFG", []string{b + "=2", a + "=1"})
+		if !reflect.DeepEqual(got, reversed) {
+			t.Fatalf("result depends on the order: %v != %v", got, reversed)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CheckEnv audits the environment entries in the "NAME=value" form of os.Environ against the
+// config struct target points to, e.g. as a preflight check before a deployment. It reports
+// variables with the prefix that do not map to any field, values that do not decode into the
+// type of their field or are not listed in its `enum` tag, and fields tagged
+// `validate:"required"` that no variable sets. Names are mapped exactly like FetchConfig
+// does, see ParseEnv, and the _FILE variables of secret fields are accepted. An empty result
+// means the environment is fine.
+//
+// Keys containing "_" or upper case letters, e.g. max_conns or maxConns, cannot be set through
+// the environment, because "_" separates the keys and names are lowercased. A variable meant
+// for such a key, e.g. CFG_PG_MAX_CONNS, is reported together with the reason.
+//
+// Only the environment is checked, required fields set by the config file or other sources
+// are reported as missing.
+func CheckEnv(prefix string, environ []string, target any) []Issue {
+	fields, err := leafFields(target)
+	if err != nil {
+		return []Issue{{Message: err.Error()}}
+	}
+	prefix = resolvePrefix(prefix)
+	env := newEnvIndex(fields)
+
+	issues := []Issue{}
+	set := map[string]bool{}
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		path, ok := EnvPath(prefix, name)
+		if !ok {
+			if strings.HasPrefix(name, prefix+"_") {
+				issues = append(issues, Issue{Message: fmt.Sprintf("%s is not a valid variable name", name)})
+			}
+			continue
+		}
+		key := strings.Join(path, ".")
+		if f, ok := env.secretFiles[key]; ok {
+			set[strings.Join(f.path, ".")] = true
+			continue
+		}
+		f, t, ok := env.lookup(path)
+		if !ok {
+			msg := fmt.Sprintf("%s sets %s, which is not a config field", name, key)
+			if f, ok := env.unreachable[strings.Join(path, "_")]; ok {
+				msg += fmt.Sprintf("; %s cannot be set through the environment", strings.Join(f.path, "."))
+			}
+			issues = append(issues, Issue{Message: msg})
+			continue
+		}
+		key = strings.Join(f.path, ".")
+		set[key] = true
+		if err := decodeEnvValue(value, t); err != nil {
+			issues = append(issues, Issue{Path: key, Message: fmt.Sprintf("%s: invalid value for %s: %s", name, t, typeErrorMessage(err))})
+			continue
+		}
+		if values := enumValues(f.field); values != nil && t == f.field.Type {
+			if err := checkEnum(reflect.ValueOf(value), values); err != nil {
+				issues = append(issues, Issue{Path: key, Message: fmt.Sprintf("%s: %s", name, err)})
+			}
+		}
+	}
+
+	for _, f := range fields {
+		key := strings.Join(f.path, ".")
+		if f.detached || set[key] || !hasRule(f.field, "required") {
+			continue
+		}
+		msg := "required but cannot be set through the environment"
+		if path, ok := env.names[key]; ok {
+			msg = fmt.Sprintf("required but %s_%s is not set", prefix, strings.ToUpper(strings.Join(path, "_")))
+		}
+		issues = append(issues, Issue{Path: key, Message: msg})
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Path != issues[j].Path {
+			return issues[i].Path < issues[j].Path
+		}
+		return issues[i].Message < issues[j].Message
+	})
+	return issues
+}
+
+// envIndex maps the key paths produced by ParseEnv to the fields they set.
+type envIndex struct {
+	// byPath holds the fields by the dotted key path a variable must map to, their yaml path
+	// or, for fields of embedded structs, their lowercased flattened path as squashEmbedded
+	// matches it.
+	byPath map[string]field
+	// secretFiles holds the secret fields by the key path of their _FILE variable.
+	secretFiles map[string]field
+	// names holds the key path of the variable setting each field by its dotted yaml path,
+	// for the fields that can be set through the environment.
+	names map[string][]string
+	// unreachable holds the fields that cannot be set through the environment by their path
+	// joined with "_", the variable name users would likely try.
+	unreachable map[string]field
+}
+
+func newEnvIndex(fields []field) *envIndex {
+	idx := &envIndex{
+		byPath:      map[string]field{},
+		secretFiles: map[string]field{},
+		names:       map[string][]string{},
+		unreachable: map[string]field{},
+	}
+	paths := map[string]bool{}
+	for _, f := range fields {
+		paths[strings.ToLower(strings.Join(f.path, "."))] = true
+	}
+	for _, f := range fields {
+		key := strings.Join(f.path, ".")
+		if envKey(f.path) {
+			idx.byPath[key] = f
+			idx.names[key] = f.path
+		}
+		if flat := strings.ToLower(strings.Join(f.envPath, ".")); !paths[flat] && envKey(strings.Split(flat, ".")) {
+			idx.byPath[flat] = f
+			idx.names[key] = strings.Split(flat, ".")
+		}
+		if _, ok := idx.names[key]; !ok {
+			idx.unreachable[strings.ToLower(strings.Join(f.path, "_"))] = f
+		}
+		if f.field.Tag.Get("secret") == "true" {
+			// readSecretFiles matches both paths lowercased
+			for _, path := range [][]string{f.path, f.envPath} {
+				idx.secretFiles[strings.ToLower(strings.Join(path, "."))+".file"] = f
+			}
+		}
+	}
+	return idx
+}
+
+// envKey reports whether a variable can map to the key path, i.e. none of its keys contains
+// "_" or upper case letters.
+func envKey(path []string) bool {
+	for _, key := range path {
+		if len(key) == 0 || strings.Contains(key, "_") || key != strings.ToLower(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the field the key path of a variable maps to and the type its value
+// decodes into. Paths below a map field map to the field with the type of the map values.
+func (idx *envIndex) lookup(path []string) (f field, t reflect.Type, ok bool) {
+	if f, ok := idx.byPath[strings.Join(path, ".")]; ok {
+		return f, f.field.Type, true
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		f, ok := idx.byPath[strings.Join(path[:i], ".")]
+		if !ok {
+			continue
+		}
+		ft := f.field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Map:
+			return f, ft.Elem(), true
+		case reflect.Interface:
+			return f, ft, true
+		}
+		return field{}, nil, false
+	}
+	return field{}, nil, false
+}
+
+// decodeEnvValue decodes the value of a variable into a value of type t the way the merged
+// config is decoded.
+func decodeEnvValue(value string, t reflect.Type) error {
+	raw, err := yaml.Marshal(parseValue(value))
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(raw, reflect.New(t).Interface())
+}
@@ -0,0 +1,99 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type EnvCheckBase struct {
+	Region string `yaml:"region"`
+}
+
+type envCheckConfig struct {
+	EnvCheckBase `yaml:"base"`
+	PG           struct {
+		Host     string `yaml:"host" validate:"required"`
+		Port     int    `yaml:"port"`
+		MaxConns int    `yaml:"max_conns"`
+		Password string `yaml:"password" secret:"true" validate:"required"`
+	} `yaml:"pg"`
+	LogLevel string         `yaml:"logLevel" enum:"debug,info"`
+	Level    string         `yaml:"level" enum:"debug,info"`
+	Labels   map[string]int `yaml:"labels"`
+	Token    string         `yaml:"api_token" validate:"required"`
+}
+
+func TestCheckEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    []Issue
+	}{
+		{
+			name:    "valid",
+			environ: []string{"CFG_PG_HOST=db", "CFG_PG_PORT=5432", "CFG_PG_PASSWORD_FILE=/run/secrets/pg", "CFG_REGION=eu", "CFG_BASE_REGION=eu", "CFG_LABELS_A=1", "HOME=/root"},
+			want:    []Issue{{Path: "api_token", Message: "required but cannot be set through the environment"}},
+		},
+		{
+			name:    "keys that cannot be set",
+			environ: []string{"CFG_PG_HOST=db", "CFG_PG_PASSWORD=x", "CFG_PG_MAX_CONNS=10", "CFG_LOGLEVEL=info", "CFG_API_TOKEN=t"},
+			want: []Issue{
+				{Message: "CFG_API_TOKEN sets api.token, which is not a config field; api_token cannot be set through the environment"},
+				{Message: "CFG_LOGLEVEL sets loglevel, which is not a config field; logLevel cannot be set through the environment"},
+				{Message: "CFG_PG_MAX_CONNS sets pg.max.conns, which is not a config field; pg.max_conns cannot be set through the environment"},
+				{Path: "api_token", Message: "required but cannot be set through the environment"},
+			},
+		},
+		{
+			name:    "unknown and invalid values",
+			environ: []string{"CFG_PG_HOST=db", "CFG_PG_PASSWORD=x", "CFG_PG_PORT=abc", "CFG_LEVEL=trace", "CFG_LABELS_B=x", "CFG_NOPE=1", "CFG_PG__HOST=a", "CFG_PG=x"},
+			want: []Issue{
+				{Message: "CFG_NOPE sets nope, which is not a config field"},
+				{Message: "CFG_PG sets pg, which is not a config field"},
+				{Message: "CFG_PG__HOST is not a valid variable name"},
+				{Path: "api_token", Message: "required but cannot be set through the environment"},
+				{Path: "labels", Message: "CFG_LABELS_B: invalid value for int: cannot unmarshal !!str `x` into int"},
+				{Path: "level", Message: `CFG_LEVEL: "trace" is not one of debug, info`},
+				{Path: "pg.port", Message: "CFG_PG_PORT: invalid value for int: cannot unmarshal !!str `abc` into int"},
+			},
+		},
+		{
+			name:    "missing required",
+			environ: []string{},
+			want: []Issue{
+				{Path: "api_token", Message: "required but cannot be set through the environment"},
+				{Path: "pg.host", Message: "required but CFG_PG_HOST is not set"},
+				{Path: "pg.password", Message: "required but CFG_PG_PASSWORD is not set"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckEnv("CFG", tt.environ, &envCheckConfig{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckEnv() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCheckEnvMatchesFetchConfig checks that every variable CheckEnv accepts is applied by
+// FetchConfig.
+func TestCheckEnvMatchesFetchConfig(t *testing.T) {
+	environ := []string{"CFG_PG_HOST=db", "CFG_PG_PORT=5432", "CFG_PG_PASSWORD=x", "CFG_REGION=eu", "CFG_LABELS_A=1", "CFG_LEVEL=info"}
+	if issues := CheckEnv("CFG", environ, &envCheckConfig{}); len(issues) != 1 || issues[0].Path != "api_token" {
+		t.Fatalf("CheckEnv() = %v, want only the unreachable api_token", issues)
+	}
+	for _, e := range environ {
+		name, value, _ := strings.Cut(e, "=")
+		t.Setenv(name, value)
+	}
+	cfg := &envCheckConfig{}
+	if err := FetchConfig("", "CFG", cfg, WithOverride("api_token", "t")); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PG.Host != "db" || cfg.PG.Port != 5432 || cfg.Region != "eu" || cfg.Labels["a"] != 1 || cfg.Level != "info" {
+		t.Errorf("FetchConfig() = %+v, want all variables applied", cfg)
+	}
+}
@@ -0,0 +1,168 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithEnvconfigTags additionally reads environment variables named after the `envconfig`,
+// `split_words` and `ignored` tags of github.com/kelseyhightower/envconfig, so config structs
+// migrated from that library keep working without re-tagging every field.
+//
+// The variables are resolved like envconfig.Process does with the same prefix, e.g. a field
+// MaxConns tagged `split_words:"true"` inside the field PG is read from CFG_PG_MAX_CONNS, and
+// a field tagged `envconfig:"DATABASE_URL"` falls back to DATABASE_URL when the prefixed
+// variable is not set. The values are put at the yaml path of the field and take precedence
+// over the plain environment variables.
+//
+// This option has only an effect in FetchConfig, since the struct is needed to derive the names.
+func WithEnvconfigTags() Option {
+	return func(o *options) {
+		o.envconfig = true
+	}
+}
+
+var (
+	envconfigWordRegexp    = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
+	envconfigAcronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
+)
+
+// readFromEnvconfigTags reads the environment variables derived from the envconfig tags of
+// the struct pointed to by cfg.
+func readFromEnvconfigTags(prefix string, cfg any) (map[string]any, error) {
+	t := reflect.TypeOf(cfg)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("cfg must be a pointer to a struct, got %T", cfg)
+	}
+	config := map[string]any{}
+	if err := readEnvconfigStruct(t.Elem(), prefix, nil, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func readEnvconfigStruct(t reflect.Type, envPrefix string, path []string, config map[string]any) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("ignored") == "true" {
+			continue
+		}
+		name, inline, ok := yamlKey(sf)
+		if !ok {
+			continue
+		}
+		if inline && !isNestedStruct(sf.Type) {
+			// an inline map holds the unknown keys, it has no path of its own
+			continue
+		}
+		fieldPath := path
+		if !inline {
+			fieldPath = append(append([]string{}, path...), name)
+		}
+		alt := strings.ToUpper(sf.Tag.Get("envconfig"))
+		key := envconfigKey(envPrefix, sf)
+
+		if isNestedStruct(sf.Type) {
+			innerPrefix := envPrefix
+			if !sf.Anonymous {
+				innerPrefix = key
+			}
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if err := readEnvconfigStruct(ft, innerPrefix, fieldPath, config); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(key)
+		if !ok && len(alt) != 0 {
+			raw, ok = os.LookupEnv(alt)
+		}
+		if !ok {
+			continue
+		}
+		value, err := envconfigValue(sf.Type, raw)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse %s", key)
+		}
+		setPath(config, fieldPath, value)
+	}
+	return nil
+}
+
+// envconfigKey returns the environment variable name envconfig uses for the field.
+func envconfigKey(envPrefix string, sf reflect.StructField) string {
+	key := sf.Name
+	if sf.Tag.Get("split_words") == "true" {
+		words := envconfigWordRegexp.FindAllStringSubmatch(sf.Name, -1)
+		if len(words) > 0 {
+			name := []string{}
+			for _, word := range words {
+				if m := envconfigAcronymRegexp.FindStringSubmatch(word[0]); len(m) == 3 {
+					name = append(name, m[1], m[2])
+				} else {
+					name = append(name, word[0])
+				}
+			}
+			key = strings.Join(name, "_")
+		}
+	}
+	if alt := sf.Tag.Get("envconfig"); len(alt) != 0 {
+		key = alt
+	}
+	if len(envPrefix) != 0 {
+		key = fmt.Sprintf("%s_%s", envPrefix, key)
+	}
+	return strings.ToUpper(key)
+}
+
+// envconfigValue parses raw into a value for a field of type t. Like envconfig, slices are
+// comma separated and maps are comma separated key:value pairs.
+func envconfigValue(t reflect.Type, raw string) (any, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return raw, nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		items := []any{}
+		if len(raw) == 0 {
+			return items, nil
+		}
+		for _, item := range strings.Split(raw, ",") {
+			value, err := typedValue(t.Elem(), item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case t.Kind() == reflect.Map:
+		m := map[string]any{}
+		if len(raw) == 0 {
+			return m, nil
+		}
+		for _, pair := range strings.Split(raw, ",") {
+			k, v, ok := strings.Cut(pair, ":")
+			if !ok {
+				return nil, errors.Errorf("invalid map item %q", pair)
+			}
+			value, err := typedValue(t.Elem(), v)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = value
+		}
+		return m, nil
+	}
+	return typedValue(t, raw)
+}
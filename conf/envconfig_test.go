@@ -0,0 +1,123 @@
+package conf
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnvconfigKey(t *testing.T) {
+	type fields struct {
+		MaxConns     int    `split_words:"true"`
+		HTTPServer   string `split_words:"true"`
+		APIKeyID     string `split_words:"true"`
+		ID           string `split_words:"true"`
+		Version2     string `split_words:"true"`
+		NotSplit     string
+		AltName      string `envconfig:"database_url"`
+		SplitAltName string `split_words:"true" envconfig:"DSN"`
+	}
+	tests := []struct {
+		field  string
+		prefix string
+		want   string
+	}{
+		{field: "MaxConns", prefix: "CFG", want: "CFG_MAX_CONNS"},
+		{field: "HTTPServer", prefix: "CFG", want: "CFG_HTTP_SERVER"},
+		{field: "APIKeyID", prefix: "CFG", want: "CFG_API_KEY_ID"},
+		{field: "ID", prefix: "CFG", want: "CFG_ID"},
+		{field: "Version2", prefix: "CFG", want: "CFG_VERSION2"},
+		{field: "NotSplit", prefix: "CFG", want: "CFG_NOTSPLIT"},
+		{field: "MaxConns", prefix: "", want: "MAX_CONNS"},
+		{field: "AltName", prefix: "CFG", want: "CFG_DATABASE_URL"},
+		{field: "SplitAltName", prefix: "CFG_PG", want: "CFG_PG_DSN"},
+	}
+	typ := reflect.TypeOf(fields{})
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			sf, _ := typ.FieldByName(tt.field)
+			if got := envconfigKey(tt.prefix, sf); got != tt.want {
+				t.Errorf("envconfigKey(%q, %s) = %q, want %q", tt.prefix, tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+type envconfigPG struct {
+	Host     string         `yaml:"host" envconfig:"DATABASE_HOST"`
+	MaxConns int            `yaml:"max_conns" split_words:"true"`
+	Timeout  time.Duration  `yaml:"timeout"`
+	Hosts    []string       `yaml:"hosts"`
+	Labels   map[string]int `yaml:"labels"`
+	// SkippedValue cannot be set by the plain variables either, its key contains "_"
+	SkippedValue string `yaml:"skipped_value" split_words:"true" ignored:"true"`
+}
+
+type envconfigConfig struct {
+	PG    envconfigPG `yaml:"pg"`
+	Debug bool        `yaml:"debug"`
+}
+
+func TestWithEnvconfigTags(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want envconfigPG
+	}{
+		{
+			name: "split words",
+			env:  map[string]string{"ENVCFGTEST_PG_MAX_CONNS": "10"},
+			want: envconfigPG{MaxConns: 10},
+		},
+		{
+			name: "the alt name is a fallback",
+			env:  map[string]string{"DATABASE_HOST": "alt"},
+			want: envconfigPG{Host: "alt"},
+		},
+		{
+			name: "the prefixed name wins over the alt name",
+			env:  map[string]string{"DATABASE_HOST": "alt", "ENVCFGTEST_PG_DATABASE_HOST": "prefixed"},
+			want: envconfigPG{Host: "prefixed"},
+		},
+		{
+			name: "envconfig names win over plain names",
+			env:  map[string]string{"ENVCFGTEST_PG_HOST": "plain", "DATABASE_HOST": "alt"},
+			want: envconfigPG{Host: "alt"},
+		},
+		{
+			name: "lists, maps and durations",
+			env: map[string]string{
+				"ENVCFGTEST_PG_HOSTS":   "a,b",
+				"ENVCFGTEST_PG_LABELS":  "x:1,y:2",
+				"ENVCFGTEST_PG_TIMEOUT": "5s",
+			},
+			want: envconfigPG{Hosts: []string{"a", "b"}, Labels: map[string]int{"x": 1, "y": 2}, Timeout: 5 * time.Second},
+		},
+		{
+			name: "ignored fields",
+			env:  map[string]string{"ENVCFGTEST_PG_SKIPPED_VALUE": "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := &envconfigConfig{}
+			if err := FetchConfig("", "ENVCFGTEST", cfg, WithEnvconfigTags()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.PG, tt.want) {
+				t.Errorf("pg = %+v, want %+v", cfg.PG, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithEnvconfigTagsInvalidValue(t *testing.T) {
+	t.Setenv("ENVCFGTEST_PG_MAX_CONNS", "many")
+	err := FetchConfig("", "ENVCFGTEST", &envconfigConfig{}, WithEnvconfigTags())
+	if err == nil {
+		t.Fatal("FetchConfig() = nil, want an error")
+	}
+}
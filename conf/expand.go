@@ -0,0 +1,118 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// expandPaths expands a leading "~" and $VARS in the string fields of cfg tagged
+// `expand:"true"`, e.g. "~/certs/$ENV.pem" becomes "/home/app/certs/prod.pem". Unset
+// variables expand to the empty string. Slices of strings are expanded element by element.
+func expandPaths(cfg any) error {
+	fields, err := leafFields(cfg)
+	if err != nil {
+		// only structs have tags
+		return nil
+	}
+	for _, f := range fields {
+		if f.field.Tag.Get("expand") != "true" {
+			continue
+		}
+		if err := rewriteStrings(f.value, expandPath); err != nil {
+			return errors.Wrapf(err, "failed to expand %s", strings.Join(f.path, "."))
+		}
+	}
+	return nil
+}
+
+// rewriteStrings replaces the string v holds, or the strings of a slice, by the result of fn.
+func rewriteStrings(v reflect.Value, fn func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return rewriteStrings(v.Elem(), fn)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := rewriteStrings(v.Index(i), fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		s, err := fn(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+		return nil
+	}
+	return errors.Errorf("only strings are supported, got %s", v.Type())
+}
+
+// expandPath expands a leading "~" to the home directory and $VAR or ${VAR} to the value of
+// the environment variable VAR.
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return home + path[1:], nil
+}
+
+// PathSource is a Source read from a file. Relative paths it sets in fields tagged
+// `relative:"true"` are resolved against the directory of the file.
+type PathSource interface {
+	Source
+	Path() string
+}
+
+// resolveRelative resolves the relative paths in the fields of cfg tagged `relative:"true"`
+// against the directory of the file that set them, i.e. the config file or a PathSource.
+// Values set by other layers, e.g. environment variables and flags, stay relative to the
+// working directory.
+func resolveRelative(cfg any, m *merged) error {
+	fields, err := leafFields(cfg)
+	if err != nil {
+		// only structs have tags
+		return nil
+	}
+	for _, f := range fields {
+		if f.field.Tag.Get("relative") != "true" {
+			continue
+		}
+		key := strings.Join(f.path, ".")
+		dir, ok := m.dirs[m.origins[key]]
+		if !ok {
+			continue
+		}
+		err := rewriteStrings(f.value, func(path string) (string, error) {
+			if len(path) == 0 || filepath.IsAbs(path) {
+				return path, nil
+			}
+			return filepath.Join(dir, path), nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", key)
+		}
+	}
+	return nil
+}
+
+// fileDir returns the directory relative paths in the file at path are resolved against.
+func fileDir(path string) string {
+	dir := filepath.Dir(path)
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
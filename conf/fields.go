@@ -0,0 +1,116 @@
+package conf
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// field is a leaf field of a config struct together with its yaml key path.
+type field struct {
+	path []string
+	// envPath is path without the keys of embedded structs, which are flattened into their
+	// parent in environment variable names like envconfig and mapstructure's squash do.
+	envPath []string
+	// goPath is path with the Go names of the fields instead of the yaml keys.
+	goPath []string
+	// detached is true for the fields of a nil pointer to a struct, their value is not part
+	// of the config.
+	detached bool
+	field    reflect.StructField
+	value    reflect.Value
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// leafFields walks the struct pointed to by cfg and returns all of its leaf fields.
+// Nested structs are expanded, structs that decode themselves (e.g. time.Time) are leaves.
+func leafFields(cfg any) ([]field, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("cfg must be a non-nil pointer to a struct, got %T", cfg)
+	}
+	fields := []field{}
+	collectFields(v.Elem(), field{}, &fields)
+	return fields, nil
+}
+
+// collectFields appends the leaf fields of the struct v to fields, parent holds the paths of v.
+func collectFields(v reflect.Value, parent field, fields *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, inline, ok := yamlKey(sf)
+		if !ok {
+			continue
+		}
+		f := field{path: parent.path, envPath: parent.envPath, goPath: parent.goPath, detached: parent.detached, field: sf}
+		if !inline {
+			f.path = append(append([]string{}, parent.path...), name)
+			f.goPath = append(append([]string{}, parent.goPath...), sf.Name)
+			if !sf.Anonymous || !isNestedStruct(sf.Type) {
+				f.envPath = append(append([]string{}, parent.envPath...), name)
+			}
+		}
+		fv := v.Field(i)
+		if inline && !isNestedStruct(sf.Type) {
+			// an inline map holds the unknown keys, it has no path of its own
+			continue
+		}
+		if isNestedStruct(sf.Type) {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv = reflect.New(sf.Type.Elem())
+					f.detached = true
+				}
+				fv = fv.Elem()
+			}
+			collectFields(fv, f, fields)
+			continue
+		}
+		f.value = fv
+		*fields = append(*fields, f)
+	}
+}
+
+// yamlKey returns the key yaml.v3 uses for the struct field and whether the field is inlined.
+// ok is false if the field is ignored by yaml.
+func yamlKey(sf reflect.StructField) (name string, inline bool, ok bool) {
+	tag := sf.Tag.Get("yaml")
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "-" {
+		return "", false, false
+	}
+	for _, flag := range parts[1:] {
+		if flag == "inline" {
+			inline = true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	return name, inline, true
+}
+
+// isNestedStruct reports whether t is a struct (or a pointer to one) whose fields are
+// config keys, rather than a type that unmarshals itself from a single value.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	p := reflect.PointerTo(t)
+	return !p.Implements(yamlUnmarshalerType) && !p.Implements(textUnmarshalerType)
+}
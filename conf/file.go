@@ -0,0 +1,100 @@
+package conf
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// stdinPath is the config path that reads the config from stdin.
+const stdinPath = "-"
+
+// defaultMaxDecompressedSize is the default limit of the decompressed size of a config file.
+const defaultMaxDecompressedSize = 64 << 20
+
+// WithMaxDecompressedSize limits compressed config files, e.g. conf.yaml.gz, to n bytes once
+// decompressed. A file inflating beyond it fails to load instead of exhausting the memory
+// before the limits of WithYAMLLimits are checked. Values <= 0 keep the default of 64 MiB.
+func WithMaxDecompressedSize(n int64) Option {
+	return func(o *options) {
+		o.maxDecompressedSize = n
+	}
+}
+
+func (o *options) decompressedLimit() int64 {
+	if o.maxDecompressedSize > 0 {
+		return o.maxDecompressedSize
+	}
+	return defaultMaxDecompressedSize
+}
+
+// stdin holds what was read from stdin, it can only be read once but every load needs it.
+var stdin struct {
+	once sync.Once
+	raw  []byte
+	err  error
+}
+
+// readFile returns the content of the config file at path. Files ending in .gz, e.g.
+// conf.yaml.gz, are decompressed, failing if they exceed maxDecompressed bytes. The path "-"
+// reads stdin until EOF the first time, later calls return the same content, so a Store
+// reloads the config it was started with.
+func readFile(path string, maxDecompressed int64) ([]byte, error) {
+	if path == stdinPath {
+		stdin.once.Do(func() {
+			stdin.raw, stdin.err = io.ReadAll(os.Stdin)
+		})
+		return stdin.raw, stdin.err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress")
+	}
+	defer zr.Close()
+	// one byte more than allowed tells a file of exactly the limit from a larger one
+	raw, err := io.ReadAll(io.LimitReader(zr, maxDecompressed+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress")
+	}
+	if int64(len(raw)) > maxDecompressed {
+		return nil, errors.Errorf("decompressed config file exceeds %d bytes", maxDecompressed)
+	}
+	return raw, nil
+}
+
+// parseFile parses the content of the config file at path by the extension of path, files
+// with an unknown extension are YAML.
+func parseFile(path string, raw []byte, limits yamlLimits) (map[string]any, error) {
+	switch fileFormat(path) {
+	case ".ini":
+		return parseINI(raw)
+	case ".properties":
+		return parseProperties(raw)
+	case ".json", ".jsonc":
+		// JSON is YAML once the comments and trailing commas are gone
+		stripped, err := stripJSONC(raw)
+		if err != nil {
+			return nil, err
+		}
+		return unmarshalYAMLMap(stripped, limits)
+	}
+	return unmarshalYAMLMap(raw, limits)
+}
+
+// fileFormat returns the lowercased extension of path, ignoring a trailing .gz.
+func fileFormat(path string) string {
+	return strings.ToLower(filepath.Ext(strings.TrimSuffix(path, ".gz")))
+}
@@ -0,0 +1,107 @@
+package conf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGzip writes content gzipped to a file named name and returns its path.
+func writeGzip(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFetchConfigGzip(t *testing.T) {
+	type config struct {
+		PG struct {
+			Host string `yaml:"host"`
+		} `yaml:"pg"`
+		Name string `yaml:"name"`
+	}
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		opts    []Option
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "yaml",
+			file:    "conf.yaml.gz",
+			content: "pg:\n  host: localhost\n",
+			want:    "localhost",
+		},
+		{
+			name:    "format by the inner extension",
+			file:    "conf.properties.gz",
+			content: "pg.host=localhost\n",
+			want:    "localhost",
+		},
+		{
+			name:    "exactly the limit",
+			file:    "conf.yaml.gz",
+			content: "pg:\n  host: localhost\n",
+			opts:    []Option{WithMaxDecompressedSize(int64(len("pg:\n  host: localhost\n")))},
+			want:    "localhost",
+		},
+		{
+			name:    "over the limit",
+			file:    "conf.yaml.gz",
+			content: "pg:\n  host: localhost\n",
+			opts:    []Option{WithMaxDecompressedSize(10)},
+			wantErr: "decompressed config file exceeds 10 bytes",
+		},
+		{
+			name:    "bomb over the default limit",
+			file:    "conf.yaml.gz",
+			content: "name: " + strings.Repeat("a", defaultMaxDecompressedSize) + "\n",
+			wantErr: "decompressed config file exceeds 67108864 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeGzip(t, tt.file, []byte(tt.content))
+			cfg := &config{}
+			err := FetchConfig(path, "GZIPTEST", cfg, tt.opts...)
+			if len(tt.wantErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FetchConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.PG.Host != tt.want {
+				t.Errorf("pg.host = %q, want %q", cfg.PG.Host, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFileInvalidGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.yaml.gz")
+	if err := os.WriteFile(path, []byte("pg:\n  host: localhost\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readFile(path, defaultMaxDecompressedSize); err == nil || !strings.Contains(err.Error(), "failed to decompress") {
+		t.Fatalf("readFile() error = %v, want a decompression error", err)
+	}
+}
@@ -0,0 +1,152 @@
+package conf
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BindFlags registers a flag on fs for every leaf field of cfg. The flag is named after the
+// dotted yaml path of the field, e.g. the field `pg.host` is set with `-pg.host=localhost`.
+// The usage text is taken from the `desc` tag of the field and the current value of the
+// field is shown as the default. Go comments are not available at runtime, reflection only
+// sees struct tags, so the `desc` tag stands in for the field comment; copy the comment into
+// it to show it in the usage.
+//
+// BindFlags only registers the flags. Pass the same FlagSet to FetchConfig with WithFlagSet
+// after parsing it so that the flags set on the command line are applied to the config.
+//
+// Example:
+//
+//	type Config struct {
+//		Port int `yaml:"port" desc:"port to listen on"`
+//	}
+//
+//	cfg := Config{}
+//	fs := flag.NewFlagSet("myapp", flag.ExitOnError)
+//	if err := conf.BindFlags(fs, &cfg); err != nil {
+//		panic(err)
+//	}
+//	fs.Parse(os.Args[1:])
+//	err := conf.FetchConfig("conf.yaml", "MYAPP", &cfg, conf.WithFlagSet(fs))
+func BindFlags(fs *flag.FlagSet, cfg any) error {
+	fields, err := leafFields(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to collect config fields")
+	}
+	for _, f := range fields {
+		if !isFlagKind(f.field.Type) {
+			continue
+		}
+		fv := &flagValue{typ: f.field.Type}
+		if !f.value.IsZero() {
+			// pointers show the value they point to, not their address
+			fv.value = fmt.Sprint(reflect.Indirect(f.value).Interface())
+		}
+		usage := f.field.Tag.Get("desc")
+		if values := enumValues(f.field); values != nil {
+			usage = strings.TrimSpace(usage + " (one of " + strings.Join(values, ", ") + ")")
+		}
+		fs.Var(fv, strings.Join(f.path, "."), usage)
+	}
+	return nil
+}
+
+// WithFlagSet applies the flags of fs that were bound by BindFlags and explicitly set on the
+// command line. They are merged on top of the config file and environment variables.
+// fs must be parsed before calling FetchConfig.
+func WithFlagSet(fs *flag.FlagSet) Option {
+	return func(o *options) {
+		load := func() (map[string]any, error) {
+			config := map[string]any{}
+			fs.Visit(func(f *flag.Flag) {
+				fv, ok := f.Value.(*flagValue)
+				if !ok {
+					return
+				}
+				// the value has been checked by Set
+				value, _ := typedValue(fv.typ, fv.value)
+				setPath(config, strings.Split(f.Name, "."), value)
+			})
+			return config, nil
+		}
+		o.overlays = append(o.overlays, namedSource{name: "flags", Source: SourceFunc(load)})
+	}
+}
+
+// flagValue is the flag.Value of a config field. It keeps the raw string and checks that it
+// can be parsed into the type of the field.
+type flagValue struct {
+	typ   reflect.Type
+	value string
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *flagValue) Set(s string) error {
+	if _, err := typedValue(f.typ, s); err != nil {
+		return err
+	}
+	f.value = s
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.typ.Kind() == reflect.Bool
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func isFlagKind(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// typedValue parses s into a value of the kind of t so that it is marshalled as the right
+// YAML scalar. Durations and types implementing encoding.TextUnmarshaler are kept as strings.
+func typedValue(t reflect.Type, s string) (any, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return s, nil
+	}
+	if t == durationType {
+		if _, err := time.ParseDuration(s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, t.Bits())
+	}
+	return s, nil
+}
@@ -0,0 +1,122 @@
+package conf
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flagConfig struct {
+	PG struct {
+		Host string `yaml:"host" desc:"database host"`
+		Port *int   `yaml:"port" desc:"database port"`
+	} `yaml:"pg"`
+	Debug   bool          `yaml:"debug" desc:"enable debug logging"`
+	Level   string        `yaml:"level" desc:"log level" enum:"debug,info"`
+	Timeout time.Duration `yaml:"timeout"`
+	Tags    []string      `yaml:"tags"`
+}
+
+func newFlagConfig() *flagConfig {
+	cfg := &flagConfig{Level: "info", Timeout: time.Minute}
+	port := 5
+	cfg.PG.Port = &port
+	return cfg
+}
+
+func TestBindFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		wantHost string
+		wantPort int
+		wantDbg  bool
+		wantTime time.Duration
+	}{
+		{
+			name:     "defaults are kept when no flag is set",
+			wantHost: "file",
+			wantPort: 5,
+			wantTime: time.Minute,
+		},
+		{
+			name:     "flags are applied",
+			args:     []string{"-pg.host=flag", "-pg.port", "6", "-debug", "-timeout=5s"},
+			wantHost: "flag",
+			wantPort: 6,
+			wantDbg:  true,
+			wantTime: 5 * time.Second,
+		},
+		{
+			name:     "flags win over env",
+			args:     []string{"-pg.host=flag"},
+			env:      map[string]string{"FLAGTEST_PG_HOST": "env", "FLAGTEST_PG_PORT": "7"},
+			wantHost: "flag",
+			wantPort: 7,
+			wantTime: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := newFlagConfig()
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			if err := BindFlags(fs, cfg); err != nil {
+				t.Fatal(err)
+			}
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := FetchConfig("", "FLAGTEST", cfg, WithSource(Static(map[string]any{"pg": map[string]any{"host": "file"}})), WithFlagSet(fs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.PG.Host != tt.wantHost || *cfg.PG.Port != tt.wantPort || cfg.Debug != tt.wantDbg || cfg.Timeout != tt.wantTime {
+				t.Errorf("config = {%q %d %v %v}, want {%q %d %v %v}", cfg.PG.Host, *cfg.PG.Port, cfg.Debug, cfg.Timeout,
+					tt.wantHost, tt.wantPort, tt.wantDbg, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestBindFlagsInvalidValue(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := BindFlags(fs, newFlagConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-pg.port=abc"}); err == nil {
+		t.Fatal("Parse() = nil, want an error for an invalid int")
+	}
+}
+
+func TestBindFlagsUsage(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := BindFlags(fs, newFlagConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Lookup("tags") != nil {
+		t.Error("lists must not be bound as flags")
+	}
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	usage := buf.String()
+	for _, want := range []string{
+		"-pg.host value\n    \tdatabase host\n",
+		"-pg.port value\n    \tdatabase port (default 5)\n",
+		"-debug\n    \tenable debug logging\n",
+		"-level value\n    \tlog level (one of debug, info) (default info)\n",
+		"-timeout value\n    \t (default 1m0s)\n",
+	} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("usage does not contain %q:\n%s", want, usage)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package conf
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// global is the singleton Store of one config type.
+type global struct {
+	once  sync.Once
+	store atomic.Pointer[Store]
+	err   error
+}
+
+var (
+	globalsMu sync.Mutex
+	globals   = map[reflect.Type]*global{}
+)
+
+func globalOf[T any]() *global {
+	globalsMu.Lock()
+	defer globalsMu.Unlock()
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	g, ok := globals[t]
+	if !ok {
+		g = &global{}
+		globals[t] = g
+	}
+	return g
+}
+
+// Init loads the global config of type T into a Store, see NewStore. Only the first call for
+// a type loads the config, later calls return the outcome of the first one, so Init can be
+// called safely from several places or goroutines.
+//
+// Example:
+//
+//	func main() {
+//		if err := conf.Init[Config]("conf.yaml", "MYAPP"); err != nil {
+//			panic(err)
+//		}
+//		...
+//	}
+//
+//	func handler() {
+//		cfg := conf.Get[Config]()
+//		...
+//	}
+func Init[T any](configPath string, envPrefix string, opts ...Option) error {
+	g := globalOf[T]()
+	g.once.Do(func() {
+		var store *Store
+		store, g.err = NewStore(configPath, envPrefix, new(T), opts...)
+		g.store.Store(store)
+	})
+	return g.err
+}
+
+// Get returns the current global config of type T, reflecting the last successful reload of
+// its Store. The returned config must not be modified. Get panics if Init was not called
+// successfully for T.
+func Get[T any]() *T {
+	return GlobalStore[T]().Get().(*T)
+}
+
+// GlobalStore returns the Store of the global config of type T, e.g. to reload it or to serve
+// its status. It panics if Init was not called successfully for T.
+func GlobalStore[T any]() *Store {
+	store := globalOf[T]().store.Load()
+	if store == nil {
+		panic(errors.Errorf("conf: Init was not called successfully for %v", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	return store
+}
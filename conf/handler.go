@@ -0,0 +1,54 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// handlerResponse is the JSON document served by Handler.
+type handlerResponse struct {
+	// Config is the effective config with the secret fields redacted.
+	Config map[string]any `json:"config"`
+	// Provenance maps every key of Config to the layer that set it.
+	Provenance map[string]string `json:"provenance"`
+	LoadedAt   time.Time         `json:"loadedAt"`
+	LastReload *ReloadStatus     `json:"lastReload"`
+}
+
+// Handler returns an http.Handler serving the effective config of store as JSON, meant to be
+// mounted under an internal route such as /debug/config. The response contains the merged
+// config with the fields tagged `secret:"true"` redacted, the layer each key was set by,
+// e.g. "file:conf.yaml" or "env:CFG", and the outcome of the last reload.
+//
+// The handler must not be exposed publicly, the config may contain sensitive values that are
+// not tagged as secrets.
+func Handler(store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		store.mu.RLock()
+		resp := handlerResponse{
+			Config:     redactMap(store.merged.config, secretPaths(store.cfg)),
+			Provenance: store.merged.origins,
+			LoadedAt:   store.loadedAt,
+			LastReload: store.lastReload,
+		}
+		buf := bytes.Buffer{}
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(resp)
+		store.mu.RUnlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
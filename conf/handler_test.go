@@ -0,0 +1,32 @@
+package conf
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerRedactsSecrets(t *testing.T) {
+	for name, broken := range brokenSecretConfigs {
+		t.Run(name, func(t *testing.T) {
+			store := newFailedStore(t, broken)
+			rec := httptest.NewRecorder()
+			Handler(store).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Handler() code = %d, want %d", rec.Code, http.StatusOK)
+			}
+			resp := handlerResponse{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.LastReload == nil || len(resp.LastReload.Error) == 0 {
+				t.Fatalf("lastReload = %+v, want the reload error", resp.LastReload)
+			}
+			if body := rec.Body.String(); strings.Contains(body, "hunter2") || strings.Contains(body, "104 117 110") {
+				t.Errorf("Handler() leaks the secret: %s", body)
+			}
+		})
+	}
+}
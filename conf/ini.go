@@ -0,0 +1,89 @@
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseINI parses an INI file into a nested config map. Keys before the first section are
+// top-level keys, the keys of a section are nested under it, and dots in section names nest
+// further, so host in [pg.replica] sets pg.replica.host.
+//
+// Lines starting with ";" or "#" are comments. Values may be quoted with " or ', otherwise
+// they are converted to an int or a bool like environment variables are.
+func parseINI(raw []byte) (map[string]any, error) {
+	config := map[string]any{}
+	var section []string
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if len(line) == 0 || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, errors.Errorf("line %d: unterminated section %q", n, line)
+			}
+			section = nil
+			for _, key := range strings.Split(line[1:len(line)-1], ".") {
+				key = strings.TrimSpace(key)
+				if len(key) == 0 {
+					return nil, errors.Errorf("line %d: invalid section %q", n, line)
+				}
+				section = append(section, key)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, errors.Errorf("line %d: expected key = value, got %q", n, line)
+		}
+		key = strings.TrimSpace(key)
+		if len(key) == 0 {
+			return nil, errors.Errorf("line %d: empty key", n)
+		}
+		path := append(append([]string{}, section...), key)
+		if err := setFilePath(config, path, fileValue(strings.TrimSpace(value))); err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// fileValue converts a raw value of a flat file format like INI. Quoted values are kept as
+// strings, other values are parsed like environment variables.
+func fileValue(value string) any {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return parseValue(value)
+}
+
+// setFilePath sets value at path, failing if the path crosses a value that is not a map.
+func setFilePath(m map[string]any, path []string, value any) error {
+	for i, key := range path[:len(path)-1] {
+		if _, ok := m[key]; !ok {
+			m[key] = map[string]any{}
+		}
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			return errors.Errorf("%s is not a section", strings.Join(path[:i+1], "."))
+		}
+		m = next
+	}
+	last := path[len(path)-1]
+	if _, ok := m[last].(map[string]any); ok {
+		return errors.Errorf("%s is a section", strings.Join(path, "."))
+	}
+	m[last] = value
+	return nil
+}
@@ -0,0 +1,94 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "top-level keys and sections",
+			raw:  "name = svc\n\n[pg]\nhost = localhost\nport = 5432\ndebug = true\n",
+			want: map[string]any{"name": "svc", "pg": map[string]any{"host": "localhost", "port": 5432, "debug": true}},
+		},
+		{
+			name: "dotted sections nest",
+			raw:  "[pg.replica]\nhost = r1\n",
+			want: map[string]any{"pg": map[string]any{"replica": map[string]any{"host": "r1"}}},
+		},
+		{
+			name: "comments and byte order mark",
+			raw:  "\xef\xbb\xbf; comment\n# comment\n  ; indented\nkey = value ; not a comment\n",
+			want: map[string]any{"key": "value ; not a comment"},
+		},
+		{
+			name: "quoted values stay strings",
+			raw:  "port = \"5432\"\ndebug = 'true'\nempty = \"\"\nhalf = \"x\n",
+			want: map[string]any{"port": "5432", "debug": "true", "empty": "", "half": "\"x"},
+		},
+		{
+			name: "values may contain =",
+			raw:  "dsn = user=admin password=x\n",
+			want: map[string]any{"dsn": "user=admin password=x"},
+		},
+		{
+			name: "sections may be repeated",
+			raw:  "[pg]\nhost = a\n[redis]\nhost = b\n[pg]\nport = 1\n",
+			want: map[string]any{"pg": map[string]any{"host": "a", "port": 1}, "redis": map[string]any{"host": "b"}},
+		},
+		{
+			name:    "unterminated section",
+			raw:     "[pg\nhost = a\n",
+			wantErr: "line 1: unterminated section",
+		},
+		{
+			name:    "empty section name",
+			raw:     "[pg..replica]\n",
+			wantErr: "line 1: invalid section",
+		},
+		{
+			name:    "missing =",
+			raw:     "a = 1\nhost\n",
+			wantErr: "line 2: expected key = value",
+		},
+		{
+			name:    "empty key",
+			raw:     " = 1\n",
+			wantErr: "line 1: empty key",
+		},
+		{
+			name:    "key and section collide",
+			raw:     "pg = 1\n[pg]\nhost = a\n",
+			wantErr: "line 3: pg is not a section",
+		},
+		{
+			name:    "section and key collide",
+			raw:     "[pg.replica]\nhost = a\n[pg]\nreplica = 1\n",
+			wantErr: "line 4: pg.replica is a section",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseINI([]byte(tt.raw))
+			if len(tt.wantErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseINI() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseINI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package conf
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// stripJSONC removes the comments and trailing commas of JSONC, i.e. JSON with // and /* */
+// comments and commas before a closing bracket, so that it can be parsed as plain JSON.
+// Comments are replaced by spaces and their line breaks are kept, so positions in errors
+// still point to the original file.
+func stripJSONC(raw []byte) ([]byte, error) {
+	out := make([]byte, len(raw))
+	copy(out, raw)
+	// comma is the position of the last comma that may turn out to be trailing, or -1
+	comma := -1
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			comma = -1
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(out) {
+				return nil, errors.New("unterminated string")
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, errors.New("unterminated comment")
+			}
+			end += i + 4
+			for ; i < end; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		case c == ',':
+			comma = i
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				out[comma] = ' '
+			}
+			comma = -1
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		default:
+			comma = -1
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,86 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "plain JSON",
+			raw:  `{"pg": {"host": "localhost", "port": 5432}}`,
+			want: map[string]any{"pg": map[string]any{"host": "localhost", "port": 5432}},
+		},
+		{
+			name: "line and block comments",
+			raw:  "{\n  // the host\n  \"host\": \"a\", /* inline */ \"port\": 1\n  /* multi\n     line */\n}",
+			want: map[string]any{"host": "a", "port": 1},
+		},
+		{
+			name: "trailing commas",
+			raw:  "{\"list\": [1, 2, 3,], \"pg\": {\"host\": \"a\",\n},\n}",
+			want: map[string]any{"list": []any{1, 2, 3}, "pg": map[string]any{"host": "a"}},
+		},
+		{
+			name: "trailing comma before a comment",
+			raw:  "{\"a\": 1, // last\n}",
+			want: map[string]any{"a": 1},
+		},
+		{
+			name: "comments in strings are kept",
+			raw:  `{"url": "http://host/path", "glob": "/*.go", "end": "*/"}`,
+			want: map[string]any{"url": "http://host/path", "glob": "/*.go", "end": "*/"},
+		},
+		{
+			name: "commas in strings are kept",
+			raw:  `{"list": "a,]", "b": "x,}"}`,
+			want: map[string]any{"list": "a,]", "b": "x,}"},
+		},
+		{
+			name: "escaped quotes in strings",
+			raw:  `{"quote": "say \"hi\" // not a comment", "slash": "c:\\"}`,
+			want: map[string]any{"quote": `say "hi" // not a comment`, "slash": `c:\`},
+		},
+		{
+			name:    "unterminated string",
+			raw:     `{"a": "x}`,
+			wantErr: "unterminated string",
+		},
+		{
+			name:    "unterminated comment",
+			raw:     "{\"a\": 1 /* x }",
+			wantErr: "unterminated comment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stripped, err := stripJSONC([]byte(tt.raw))
+			if len(tt.wantErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("stripJSONC() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stripped) != len(tt.raw) || strings.Count(string(stripped), "\n") != strings.Count(tt.raw, "\n") {
+				t.Errorf("stripJSONC() moved positions: %q", stripped)
+			}
+			got, err := unmarshalYAMLMap(stripped, newOptions(nil).yamlLimits())
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", stripped, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stripJSONC() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
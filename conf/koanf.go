@@ -0,0 +1,110 @@
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// KoanfEnvProvider implements koanf.Provider on top of the environment variable convention
+// of FetchConfig, e.g. CFG_PG_HOST is provided as pg.host. Load it with
+// k.Load(conf.KoanfEnv("CFG"), nil).
+type KoanfEnvProvider struct {
+	prefix string
+}
+
+// KoanfEnv returns a koanf provider reading the environment variables with the given prefix.
+// If prefix is empty, "CFG" is used.
+func KoanfEnv(prefix string) *KoanfEnvProvider {
+	return &KoanfEnvProvider{prefix: resolvePrefix(prefix)}
+}
+
+// ReadBytes is not supported, the environment is already structured. Use Read instead.
+func (p *KoanfEnvProvider) ReadBytes() ([]byte, error) {
+	return nil, errors.New("koanf env provider does not support ReadBytes")
+}
+
+// Read returns the environment variables with the prefix as a nested map.
+func (p *KoanfEnvProvider) Read() (map[string]any, error) {
+	return readFromConfigEnv(p.prefix, nil)
+}
+
+// KoanfEnvParser implements koanf.Parser for dotenv style files with one KEY=value per line.
+// Keys are mapped with the same convention as environment variables in FetchConfig and
+// keys without the prefix are ignored. Blank lines, lines starting with # and an
+// `export ` prefix are skipped, values may be wrapped in single or double quotes.
+type KoanfEnvParser struct {
+	prefix string
+}
+
+// KoanfDotenv returns a koanf parser for dotenv files using the given prefix.
+// If prefix is empty, "CFG" is used.
+func KoanfDotenv(prefix string) *KoanfEnvParser {
+	return &KoanfEnvParser{prefix: resolvePrefix(prefix)}
+}
+
+// Unmarshal parses the dotenv content into a nested map.
+func (p *KoanfEnvParser) Unmarshal(b []byte) (map[string]any, error) {
+	environ := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, errors.Errorf("line %d: missing = in %q", n, line)
+		}
+		environ = append(environ, strings.TrimSpace(key)+"="+unquote(strings.TrimSpace(value)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to scan dotenv content")
+	}
+	return ParseEnv(p.prefix, environ), nil
+}
+
+// Marshal renders the nested map as sorted dotenv lines, e.g. pg.host is written as
+// CFG_PG_HOST=value.
+func (p *KoanfEnvParser) Marshal(m map[string]any) ([]byte, error) {
+	lines := []string{}
+	if err := flattenEnv(m, p.prefix, &lines); err != nil {
+		return nil, err
+	}
+	sort.Strings(lines)
+	buf := bytes.Buffer{}
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func flattenEnv(m map[string]any, prefix string, lines *[]string) error {
+	for k, v := range m {
+		key := prefix + "_" + strings.ToUpper(k)
+		switch t := v.(type) {
+		case map[string]any:
+			if err := flattenEnv(t, key, lines); err != nil {
+				return err
+			}
+		case []any, map[any]any:
+			return errors.Errorf("%s: lists and non-string keyed maps cannot be written as env", key)
+		default:
+			*lines = append(*lines, fmt.Sprintf("%s=%v", key, v))
+		}
+	}
+	return nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
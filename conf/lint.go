@@ -0,0 +1,327 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a problem Lint found in a config file.
+type Issue struct {
+	// Line and Column locate the problem in the file, they are 0 if it is not tied to a line.
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Path is the dotted key path the issue is about, empty for the whole file.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Line != 0 {
+		fmt.Fprintf(&b, "line %d:%d: ", i.Line, i.Column)
+	}
+	if len(i.Path) != 0 {
+		fmt.Fprintf(&b, "%s: ", i.Path)
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+var yamlLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+// Lint checks the config file at path against the config struct target points to, without
+// loading it. The format is chosen by the extension like FetchConfig does. It reports unknown
+// keys, values that do not decode into the type of their field, values not listed in the
+// `enum` tag of their field and missing required fields, i.e. fields tagged
+// `validate:"required"`. An empty result means the file is fine.
+//
+// Only the file is checked, values that would be set by environment variables or other
+// sources are not taken into account. Issues in .ini and .properties files have no line
+// numbers, only their key paths.
+func Lint(path string, target any) []Issue {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return []Issue{{Message: fmt.Sprintf("target must be a pointer to a struct, got %T", target)}}
+	}
+	raw, err := readFile(path, newOptions(nil).decompressedLimit())
+	if err != nil {
+		return []Issue{{Message: fmt.Sprintf("failed to read config file %s: %v", path, err)}}
+	}
+	doc, err := lintDocument(path, raw)
+	if err != nil {
+		issue := Issue{Message: err.Error()}
+		if m := yamlLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+		}
+		return []Issue{issue}
+	}
+	if err := newOptions(nil).yamlLimits().check(doc); err != nil {
+		return []Issue{{Message: err.Error()}}
+	}
+	l := &linter{}
+	if len(doc.Content) == 0 {
+		// an empty file sets nothing
+		l.missing(doc, t.Elem(), nil)
+	} else {
+		l.check(doc.Content[0], t.Elem(), nil)
+	}
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i], l.issues[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Path < b.Path
+	})
+	return l.issues
+}
+
+// lintDocument parses the content of the config file at path into a YAML document by the
+// extension of path, like parseFile. JSON keeps its positions, the formats that are not YAML
+// at all are converted from their parsed map.
+func lintDocument(path string, raw []byte) (*yaml.Node, error) {
+	switch fileFormat(path) {
+	case ".ini", ".properties":
+		config, err := parseFile(path, raw, newOptions(nil).yamlLimits())
+		if err != nil {
+			return nil, err
+		}
+		content := &yaml.Node{}
+		if err := content.Encode(config); err != nil {
+			return nil, err
+		}
+		return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{content}}, nil
+	case ".json", ".jsonc":
+		stripped, err := stripJSONC(raw)
+		if err != nil {
+			return nil, err
+		}
+		raw = stripped
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(raw, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+type linter struct {
+	issues []Issue
+}
+
+func (l *linter) report(n *yaml.Node, path []string, format string, args ...any) {
+	l.issues = append(l.issues, Issue{
+		Line:    n.Line,
+		Column:  n.Column,
+		Path:    strings.Join(path, "."),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// check reports the issues of node n, which is decoded into a value of type t.
+func (l *linter) check(n *yaml.Node, t reflect.Type, path []string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Tag == "!!null" {
+		return
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case isNestedStruct(t):
+		if n.Kind != yaml.MappingNode {
+			l.report(n, path, "expected a mapping, got %s", nodeKind(n))
+			return
+		}
+		seen := map[string]bool{}
+		l.checkStruct(n, t, path, seen)
+		l.missingFields(n, t, path, seen)
+	case t.Kind() == reflect.Map && !reflect.PointerTo(t).Implements(yamlUnmarshalerType):
+		if n.Kind != yaml.MappingNode {
+			l.report(n, path, "expected a mapping, got %s", nodeKind(n))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			l.check(n.Content[i+1], t.Elem(), append(path, n.Content[i].Value))
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 &&
+		!reflect.PointerTo(t).Implements(yamlUnmarshalerType):
+		if n.Kind != yaml.SequenceNode {
+			l.report(n, path, "expected a sequence, got %s", nodeKind(n))
+			return
+		}
+		for i, item := range n.Content {
+			l.check(item, t.Elem(), append(path, strconv.Itoa(i)))
+		}
+	default:
+		if err := n.Decode(reflect.New(t).Interface()); err != nil {
+			l.report(n, path, "invalid value for %s: %s", t, typeErrorMessage(err))
+		}
+	}
+}
+
+// checkStruct checks the keys of the mapping n against the fields of the struct type t and
+// records the keys it sets in seen.
+func (l *linter) checkStruct(n *yaml.Node, t reflect.Type, path []string, seen map[string]bool) {
+	fields, inlineMap := structKeys(t)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Tag == "!!merge" {
+			merged := value
+			if merged.Kind == yaml.AliasNode {
+				merged = merged.Alias
+			}
+			if merged.Kind == yaml.MappingNode {
+				l.checkStruct(merged, t, path, seen)
+			}
+			continue
+		}
+		keyPath := append(append([]string{}, path...), key.Value)
+		sf, ok := fields[key.Value]
+		if !ok {
+			if inlineMap != nil {
+				l.check(value, inlineMap.Elem(), keyPath)
+				continue
+			}
+			l.report(key, keyPath, "unknown key")
+			continue
+		}
+		seen[key.Value] = true
+		l.check(value, sf.Type, keyPath)
+		if values := enumValues(sf); values != nil {
+			l.checkEnum(value, values, keyPath)
+		}
+	}
+}
+
+// checkEnum reports the scalars in n that are not one of values.
+func (l *linter) checkEnum(n *yaml.Node, values []string, path []string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			l.checkEnum(item, values, append(path, strconv.Itoa(i)))
+		}
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return
+		}
+		for _, value := range values {
+			if n.Value == value {
+				return
+			}
+		}
+		l.report(n, path, "%q is not one of %s", n.Value, strings.Join(values, ", "))
+	}
+}
+
+// missingFields reports the required fields of the struct type t that are not in seen.
+func (l *linter) missingFields(n *yaml.Node, t reflect.Type, path []string, seen map[string]bool) {
+	fields, _ := structKeys(t)
+	for name, sf := range fields {
+		if seen[name] {
+			continue
+		}
+		l.missingField(n, sf, append(append([]string{}, path...), name))
+	}
+}
+
+// missing reports all required fields of the struct type t, none of which is set.
+func (l *linter) missing(n *yaml.Node, t reflect.Type, path []string) {
+	l.missingFields(n, t, path, map[string]bool{})
+}
+
+func (l *linter) missingField(n *yaml.Node, sf reflect.StructField, path []string) {
+	if hasRule(sf, "required") {
+		l.report(n, path, "required key is missing")
+		return
+	}
+	// an unset pointer to a struct is optional as a whole
+	if isNestedStruct(sf.Type) && sf.Type.Kind() != reflect.Pointer {
+		l.missing(n, sf.Type, path)
+	}
+}
+
+// structKeys returns the fields of the struct type t by their yaml key, with the fields of
+// inline structs flattened into it, and the type of the inline map if there is one.
+func structKeys(t reflect.Type) (map[string]reflect.StructField, reflect.Type) {
+	fields := map[string]reflect.StructField{}
+	var inlineMap reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, inline, ok := yamlKey(sf)
+		if !ok {
+			continue
+		}
+		if !inline {
+			fields[name] = sf
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			inner, innerMap := structKeys(ft)
+			for k, f := range inner {
+				fields[k] = f
+			}
+			if innerMap != nil {
+				inlineMap = innerMap
+			}
+		case reflect.Map:
+			inlineMap = ft
+		}
+	}
+	return fields, inlineMap
+}
+
+// hasRule reports whether the `validate` tag of sf contains rule.
+func hasRule(sf reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(sf.Tag.Get("validate"), ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeKind(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a sequence"
+	}
+	return fmt.Sprintf("%q", n.Value)
+}
+
+// typeErrorMessage returns the message of a yaml.v3 decode error without the summary line and
+// the line number, which is already part of the Issue.
+func typeErrorMessage(err error) string {
+	msg := strings.TrimSpace(err.Error())
+	if i := strings.LastIndex(msg, "\n"); i >= 0 {
+		msg = msg[i+1:]
+	}
+	msg = strings.TrimPrefix(strings.TrimSpace(msg), "yaml: ")
+	if loc := yamlLineRegexp.FindStringIndex(msg); loc != nil && loc[0] == 0 {
+		msg = strings.TrimPrefix(msg[loc[1]:], ": ")
+	}
+	return msg
+}
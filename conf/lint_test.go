@@ -0,0 +1,89 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type lintConfig struct {
+	Name string `yaml:"name" validate:"required"`
+	PG   struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	} `yaml:"pg"`
+}
+
+func TestLintFormats(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    []Issue
+	}{
+		{
+			file:    "conf.yaml",
+			content: "name: a\npg:\n  host: localhost\n  port: 5432\n",
+		},
+		{
+			file:    "conf.yaml",
+			content: "name: a\npg:\n  port: abc\n  user: x\n",
+			want: []Issue{
+				{Line: 3, Column: 9, Path: "pg.port", Message: "invalid value for int: cannot unmarshal !!str `abc` into int"},
+				{Line: 4, Column: 3, Path: "pg.user", Message: "unknown key"},
+			},
+		},
+		{
+			file:    "conf.ini",
+			content: "name = a\n\n[pg]\nhost = localhost\nport = 5432\n",
+		},
+		{
+			file:    "conf.ini",
+			content: "[pg]\nport = abc\n",
+			want: []Issue{
+				{Path: "name", Message: "required key is missing"},
+				{Path: "pg.port", Message: "invalid value for int: cannot unmarshal !!str `abc` into int"},
+			},
+		},
+		{
+			file:    "conf.properties",
+			content: "name=a\npg.host=localhost\npg.port=5432\n",
+		},
+		{
+			file:    "conf.properties",
+			content: "name=a\npg.user=x\n",
+			want:    []Issue{{Path: "pg.user", Message: "unknown key"}},
+		},
+		{
+			file:    "conf.jsonc",
+			content: "{\n  // the service\n  \"name\": \"a\",\n  \"pg\": {\"host\": \"localhost\", \"port\": 5432,},\n}\n",
+		},
+		{
+			file:    "conf.json",
+			content: "{\n  \"name\": \"a\",\n  /* unknown */\n  \"pg\": {\"user\": \"x\"}\n}\n",
+			want:    []Issue{{Line: 4, Column: 10, Path: "pg.user", Message: "unknown key"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got := Lint(path, &lintConfig{})
+			if len(tt.want) != 0 {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Lint() = %v, want %v", got, tt.want)
+				}
+				return
+			}
+			if len(got) != 0 {
+				t.Errorf("Lint() = %v, want no issues", got)
+			}
+			// a file Lint accepts must load
+			if err := FetchConfig(path, "LINTTEST", &lintConfig{}); err != nil {
+				t.Errorf("FetchConfig() = %v, but Lint() found no issues", err)
+			}
+		})
+	}
+}
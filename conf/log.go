@@ -0,0 +1,111 @@
+package conf
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WithLogger reports what the load pipeline does to l: the config files read, the sources
+// loaded, the number of environment variables applied, the deprecated keys that are set and,
+// for a Store, the keys changed by every reload. Without a logger nothing is logged.
+//
+// Fields tagged `deprecated:"message"` are reported as deprecated when set, and the values of
+// fields tagged `secret:"true"` are redacted.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func (o *options) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.New(discardHandler{})
+	}
+	return o.logger
+}
+
+// discardHandler drops all records, log/slog only ships one from Go 1.24 on.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// logDeprecated warns about every field of cfg tagged as deprecated that is set in config.
+func logDeprecated(l *slog.Logger, config map[string]any, cfg any) {
+	fields, err := leafFields(cfg)
+	if err != nil {
+		return
+	}
+	flat := flattenMap(config)
+	for _, f := range fields {
+		msg, ok := f.field.Tag.Lookup("deprecated")
+		if !ok {
+			continue
+		}
+		key := strings.Join(f.path, ".")
+		if _, set := flat[key]; set {
+			l.Warn("deprecated config key is set", "key", key, "reason", msg)
+		}
+	}
+}
+
+// logChanges logs the keys that differ between the old and new config.
+func logChanges(l *slog.Logger, old, new map[string]any, cfg any) {
+	secrets := secretPaths(cfg)
+	changes := diffMaps(old, new)
+	if len(changes) == 0 {
+		l.Debug("config reloaded without changes")
+		return
+	}
+	l.Info("config reloaded", "changes", len(changes))
+	for _, key := range changes {
+		l.Info("config key changed", "key", key,
+			"old", redactValue(secrets, key, old), "new", redactValue(secrets, key, new))
+	}
+}
+
+// flattenMap returns the leaf values of m keyed by their dotted path.
+func flattenMap(m map[string]any) map[string]any {
+	flat := map[string]any{}
+	flattenInto(m, "", flat)
+	return flat
+}
+
+func flattenInto(m map[string]any, prefix string, flat map[string]any) {
+	for k, v := range m {
+		if sub, ok := v.(map[string]any); ok {
+			flattenInto(sub, prefix+k+".", flat)
+			continue
+		}
+		flat[prefix+k] = v
+	}
+}
+
+// diffMaps returns the sorted dotted paths of the leaf values that were added, removed or
+// changed between old and new.
+func diffMaps(old, new map[string]any) []string {
+	o, n := flattenMap(old), flattenMap(new)
+	keys := []string{}
+	for k, v := range o {
+		if nv, ok := n[k]; !ok || !reflect.DeepEqual(v, nv) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range n {
+		if _, ok := o[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countLeaves returns the number of leaf values in m.
+func countLeaves(m map[string]any) int {
+	return len(flattenMap(m))
+}
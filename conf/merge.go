@@ -0,0 +1,72 @@
+package conf
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// MergeFunc merges the value of a higher layer into the value of the lower layers at the same
+// key, e.g. to concatenate lists instead of replacing them.
+type MergeFunc func(base, patch any) (any, error)
+
+// WithMergeFunc makes the layers merge the value at the dotted path with fn instead of the
+// higher layer replacing it, e.g. WithMergeFunc("extra_args", conf.Append). fn is only called
+// if both the lower and the higher layer set the key.
+func WithMergeFunc(path string, fn MergeFunc) Option {
+	return func(o *options) {
+		if o.mergeFuncs == nil {
+			o.mergeFuncs = map[string]MergeFunc{}
+		}
+		o.mergeFuncs[path] = fn
+	}
+}
+
+// Append is a MergeFunc concatenating the lists of the layers, the higher layer's items last.
+// A single value is treated like a list with one item.
+func Append(base, patch any) (any, error) {
+	b, err := toList(base)
+	if err != nil {
+		return nil, err
+	}
+	p, err := toList(patch)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]any{}, b...), p...), nil
+}
+
+// Union is a MergeFunc like Append that drops the items already in the list.
+func Union(base, patch any) (any, error) {
+	all, err := Append(base, patch)
+	if err != nil {
+		return nil, err
+	}
+	union := []any{}
+	for _, item := range all.([]any) {
+		dup := false
+		for _, u := range union {
+			if reflect.DeepEqual(u, item) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			union = append(union, item)
+		}
+	}
+	return union, nil
+}
+
+func toList(v any) ([]any, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return t, nil
+	case map[string]any:
+		// the path is added by the caller, the values may be secrets
+		return nil, errors.New("cannot merge a map as a list")
+	}
+	return []any{v}, nil
+}
@@ -0,0 +1,153 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeFuncs(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      MergeFunc
+		base    any
+		patch   any
+		want    any
+		wantErr bool
+	}{
+		{name: "append lists", fn: Append, base: []any{"a", "b"}, patch: []any{"b", "c"}, want: []any{"a", "b", "b", "c"}},
+		{name: "append single values", fn: Append, base: "a", patch: 1, want: []any{"a", 1}},
+		{name: "append to nil", fn: Append, base: nil, patch: []any{"a"}, want: []any{"a"}},
+		{name: "append nil", fn: Append, base: []any{"a"}, patch: nil, want: []any{"a"}},
+		{name: "append a map", fn: Append, base: []any{"a"}, patch: map[string]any{"k": "v"}, wantErr: true},
+		{name: "union drops duplicates", fn: Union, base: []any{"a", "b", "a"}, patch: []any{"b", "c"}, want: []any{"a", "b", "c"}},
+		{
+			name:  "union compares items deeply",
+			fn:    Union,
+			base:  []any{map[string]any{"k": 1}},
+			patch: []any{map[string]any{"k": 1}, map[string]any{"k": 2}},
+			want:  []any{map[string]any{"k": 1}, map[string]any{"k": 2}},
+		},
+		{name: "union of a map", fn: Union, base: map[string]any{"k": "v"}, patch: []any{"a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn(tt.base, tt.patch)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchMapMergeFunc(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		base      map[string]any
+		patch     map[string]any
+		want      map[string]any
+		wantCalls int
+	}{
+		{
+			name:      "both layers set the key",
+			path:      "args",
+			base:      map[string]any{"args": []any{"a"}},
+			patch:     map[string]any{"args": []any{"b"}},
+			want:      map[string]any{"args": []any{"a", "b"}},
+			wantCalls: 1,
+		},
+		{
+			name:  "the lower layer does not set the key",
+			path:  "args",
+			base:  map[string]any{},
+			patch: map[string]any{"args": []any{"b"}},
+			want:  map[string]any{"args": []any{"b"}},
+		},
+		{
+			name:  "the lower layer sets the key to null",
+			path:  "args",
+			base:  map[string]any{"args": nil},
+			patch: map[string]any{"args": []any{"b"}},
+			want:  map[string]any{"args": []any{"b"}},
+		},
+		{
+			name:  "the higher layer does not set the key",
+			path:  "args",
+			base:  map[string]any{"args": []any{"a"}},
+			patch: map[string]any{"other": 1},
+			want:  map[string]any{"args": []any{"a"}, "other": 1},
+		},
+		{
+			name:      "nested path",
+			path:      "server.args",
+			base:      map[string]any{"server": map[string]any{"args": []any{"a"}, "port": 80}},
+			patch:     map[string]any{"server": map[string]any{"args": "b", "port": 81}},
+			want:      map[string]any{"server": map[string]any{"args": []any{"a", "b"}, "port": 81}},
+			wantCalls: 1,
+		},
+		{
+			name:  "only the exact path",
+			path:  "server.args",
+			base:  map[string]any{"args": []any{"a"}, "other": map[string]any{"args": []any{"a"}}},
+			patch: map[string]any{"args": []any{"b"}, "other": map[string]any{"args": []any{"b"}}},
+			want:  map[string]any{"args": []any{"b"}, "other": map[string]any{"args": []any{"b"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			fn := func(base, patch any) (any, error) {
+				calls++
+				return Append(base, patch)
+			}
+			if err := patchMap(tt.base, tt.patch, "", map[string]MergeFunc{tt.path: fn}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.base, tt.want) {
+				t.Errorf("merged = %#v, want %#v", tt.base, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("merge func called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPatchMapMergeFuncError(t *testing.T) {
+	base := map[string]any{"server": map[string]any{"args": []any{"a"}}}
+	patch := map[string]any{"server": map[string]any{"args": map[string]any{"password": "hunter2"}}}
+	err := patchMap(base, patch, "", map[string]MergeFunc{"server.args": Append})
+	if err == nil || !strings.Contains(err.Error(), "failed to merge server.args") {
+		t.Fatalf("patchMap() = %v, want a merge error", err)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("patchMap() leaks the value: %v", err)
+	}
+}
+
+func TestWithMergeFunc(t *testing.T) {
+	cfg := &struct {
+		Server struct {
+			Args []string `yaml:"args"`
+		} `yaml:"server"`
+	}{}
+	source := Static(map[string]any{"server": map[string]any{"args": []any{"-v", "-x"}}})
+	err := FetchConfig("", "MERGETEST", cfg, WithSource(source), WithOverride("server.args", "-x"),
+		WithMergeFunc("server.args", Union))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-v", "-x"}; !reflect.DeepEqual(cfg.Server.Args, want) {
+		t.Errorf("args = %q, want %q", cfg.Server.Args, want)
+	}
+}
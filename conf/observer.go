@@ -0,0 +1,21 @@
+package conf
+
+import "time"
+
+// Observer is notified about the outcome of config loads, e.g. to export metrics.
+// See the confprom package for a Prometheus implementation.
+type Observer interface {
+	// ObserveLoad is called after every run of the load pipeline, including the initial
+	// load and every reload of a Store. err is nil if the load succeeded.
+	ObserveLoad(d time.Duration, err error)
+
+	// ObserveReload is called after every call to Store.Reload.
+	ObserveReload(err error)
+}
+
+// WithObserver registers obs to be notified about config loads and reloads.
+func WithObserver(obs Observer) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, obs)
+	}
+}
@@ -0,0 +1,96 @@
+package conf
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Option customizes how FetchConfig loads the config.
+type Option func(*options)
+
+type options struct {
+	// sources are merged on top of the config file, below environment variables.
+	sources []Source
+	// overlays are merged on top of the config file and environment variables.
+	// In both lists the last one added has the highest priority.
+	overlays []Source
+
+	// decoder replaces the YAML decoding of the merged config, nil keeps it.
+	decoder Decoder
+
+	// mergeFuncs merge the values at their dotted path instead of replacing them.
+	mergeFuncs map[string]MergeFunc
+
+	// transforms rewrite the merged config before it is decoded.
+	transforms []func(config map[string]any) (map[string]any, error)
+	// hooks run after the config was decoded and validated.
+	hooks []func(ctx context.Context, cfg any) error
+
+	// treeValidators check the merged config before it is decoded.
+	treeValidators []TreeValidator
+
+	// envconfig enables reading environment variables named after envconfig tags.
+	envconfig bool
+
+	observers []Observer
+	tracers   []Tracer
+	logger    *slog.Logger
+
+	// maxAge is the age after which the config of a Store is reported as stale.
+	maxAge time.Duration
+
+	// reloadInterval is how often a Watcher reloads the config, 0 disables it.
+	reloadInterval time.Duration
+	// ttls are the TTLs of the values re-resolved by a Watcher by their dotted path.
+	ttls map[string]time.Duration
+
+	// yamlMaxDepth and yamlMaxNodes limit the config file, see WithYAMLLimits.
+	yamlMaxDepth int
+	yamlMaxNodes int
+	// maxDecompressedSize limits compressed config files, see WithMaxDecompressedSize.
+	maxDecompressedSize int64
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithOverride forces the value of the config key at the dotted path, e.g.
+// WithOverride("pg.host", "localhost"). The value is parsed the same way as environment
+// variables and takes precedence over the config file and environment variables.
+func WithOverride(path string, value string) Option {
+	return WithOverrides(map[string]string{path: value})
+}
+
+// WithOverrides is like WithOverride but sets several keys at once.
+func WithOverrides(overrides map[string]string) Option {
+	return func(o *options) {
+		load := func() (map[string]any, error) {
+			paths := make([]string, 0, len(overrides))
+			for path := range overrides {
+				paths = append(paths, path)
+			}
+			// sorted so that a parent key is always set before its children
+			sort.Strings(paths)
+			config := map[string]any{}
+			for _, path := range paths {
+				value := overrides[path]
+				if len(path) == 0 {
+					return nil, errors.New("override path must not be empty")
+				}
+				setPath(config, strings.Split(path, "."), parseValue(value))
+			}
+			return config, nil
+		}
+		o.overlays = append(o.overlays, namedSource{name: "overrides", Source: SourceFunc(load)})
+	}
+}
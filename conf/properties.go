@@ -0,0 +1,152 @@
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseProperties parses a Java .properties file into a nested config map. Dots in keys nest,
+// so pg.host=localhost sets pg.host. If a key is a prefix of another one, e.g. logging.level
+// and logging.level.root, the longer one wins like for environment variables. Of duplicate
+// keys the last one wins, like in java.util.Properties.
+//
+// The syntax follows java.util.Properties: "#" and "!" start comments, keys and values are
+// separated by "=", ":" or whitespace, a trailing backslash continues the line, and \t, \n,
+// \uXXXX and other backslash escapes are unescaped. Values are converted to an int or a bool
+// like environment variables are.
+func parseProperties(raw []byte) (map[string]any, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if len(line) == 0 || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		start := n
+		for continues(line) && scanner.Scan() {
+			n++
+			line = line[:len(line)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+		key, value := splitProperty(line)
+		k, err := unescapeProperty(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", start)
+		}
+		v, err := unescapeProperty(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", start)
+		}
+		values[k] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// longer paths first, so that a nested key always wins over a value at its parent
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := strings.Count(keys[i], "."), strings.Count(keys[j], ".")
+		if ci != cj {
+			return ci > cj
+		}
+		return keys[i] < keys[j]
+	})
+	config := map[string]any{}
+	for _, k := range keys {
+		path := strings.Split(k, ".")
+		valid := true
+		for _, segment := range path {
+			if len(segment) == 0 {
+				valid = false
+			}
+		}
+		if !valid {
+			return nil, errors.Errorf("invalid key %q", k)
+		}
+		setEnvPath(config, path, parseValue(values[k]))
+	}
+	return config, nil
+}
+
+// continues reports whether line ends with an odd number of backslashes, i.e. it is
+// continued on the next line.
+func continues(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+// splitProperty splits a logical line into its still escaped key and value.
+func splitProperty(line string) (key, value string) {
+	i := 0
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+	}
+	if i >= len(line) {
+		return line, ""
+	}
+	key = line[:i]
+	// the separator is "=" or ":" surrounded by optional whitespace, or whitespace alone
+	rest := strings.TrimLeft(line[i:], " \t\f")
+	if len(rest) != 0 && (rest[0] == '=' || rest[0] == ':') {
+		rest = rest[1:]
+	}
+	return key, strings.TrimLeft(rest, " \t\f")
+}
+
+// unescapeProperty resolves the backslash escapes of a key or value.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", errors.Errorf("invalid escape %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", errors.Errorf("invalid escape %q", s[i-1:i+5])
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
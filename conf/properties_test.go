@@ -0,0 +1,94 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "dotted keys nest",
+			raw:  "pg.host=localhost\npg.port=5432\ndebug=true\n",
+			want: map[string]any{"pg": map[string]any{"host": "localhost", "port": 5432}, "debug": true},
+		},
+		{
+			name: "separators",
+			raw:  "a=1\nb:2\nc 3\nd = 4\ne : 5\nf\t6\ng\n",
+			want: map[string]any{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": ""},
+		},
+		{
+			name: "comments",
+			raw:  "# comment\n! comment\n   # indented\nurl=http://host/#anchor\n",
+			want: map[string]any{"url": "http://host/#anchor"},
+		},
+		{
+			name: "continuation lines",
+			raw:  "list=a,\\\n    b,\\\n    c\nnext=1\n",
+			want: map[string]any{"list": "a,b,c", "next": 1},
+		},
+		{
+			name: "escaped backslash does not continue",
+			raw:  "path=c:\\\\\nnext=1\n",
+			want: map[string]any{"path": "c:\\", "next": 1},
+		},
+		{
+			name: "escapes",
+			raw:  "tab=a\\tb\nnewline=a\\nb\nunicode=\\u00e4\\u4e2d\nother=\\q\\#\n",
+			want: map[string]any{"tab": "a\tb", "newline": "a\nb", "unicode": "ä中", "other": "q#"},
+		},
+		{
+			name: "escaped separators in keys",
+			raw:  "my\\ key\\=x\\:y=1\n",
+			want: map[string]any{"my key=x:y": 1},
+		},
+		{
+			name: "longer key wins over its parent",
+			raw:  "logging.level.root=info\nlogging.level=debug\n",
+			want: map[string]any{"logging": map[string]any{"level": map[string]any{"root": "info"}}},
+		},
+		{
+			name: "last duplicate wins",
+			raw:  "a=1\na=2\n",
+			want: map[string]any{"a": 2},
+		},
+		{
+			name:    "invalid unicode escape",
+			raw:     "a=1\nb=\\u00zz\n",
+			wantErr: "line 2: invalid escape",
+		},
+		{
+			name:    "truncated unicode escape",
+			raw:     "b=\\u00\n",
+			wantErr: "line 1: invalid escape",
+		},
+		{
+			name:    "empty key segment",
+			raw:     "pg..host=a\n",
+			wantErr: `invalid key "pg..host"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProperties([]byte(tt.raw))
+			if len(tt.wantErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseProperties() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
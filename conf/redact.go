@@ -0,0 +1,88 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the values of secret fields wherever the config is shown.
+const redacted = "<redacted>"
+
+// redactValue returns the value at the dotted key of config, redacted if the key is a secret.
+func redactValue(secrets map[string]bool, key string, config map[string]any) any {
+	v, ok := flattenMap(config)[key]
+	if !ok {
+		return nil
+	}
+	if secrets[key] {
+		return redacted
+	}
+	return v
+}
+
+// secretPaths returns the dotted paths of the fields of cfg tagged with `secret:"true"`.
+func secretPaths(cfg any) map[string]bool {
+	secrets := map[string]bool{}
+	fields, err := leafFields(cfg)
+	if err != nil {
+		return secrets
+	}
+	for _, f := range fields {
+		if f.field.Tag.Get("secret") == "true" {
+			secrets[strings.Join(f.path, ".")] = true
+		}
+	}
+	return secrets
+}
+
+// redactMap returns a copy of config with the values of the secret paths redacted.
+func redactMap(config map[string]any, secrets map[string]bool) map[string]any {
+	c := copyMap(config)
+	flat := flattenMap(c)
+	for key := range secrets {
+		if _, ok := flat[key]; ok {
+			setPath(c, strings.Split(key, "."), redacted)
+		}
+	}
+	return c
+}
+
+// Redacted returns the config cfg points to as a nested map keyed like the YAML config, with
+// the values of the fields tagged `secret:"true"` replaced by "<redacted>". It is meant for
+// showing the effective config, e.g. in logs or golden files.
+func Redacted(cfg any) (map[string]any, error) {
+	raw, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "yaml marshal error")
+	}
+	config := map[string]any{}
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal config")
+	}
+	return redactMap(config, secretPaths(cfg)), nil
+}
+
+// redactError returns err with the values of the secret paths in config replaced by
+// "<redacted>", e.g. a decode error quoting the value it failed on. Errors are served by the
+// status and debug handlers, which must not leak secrets. err is returned as it is if it
+// contains none of them.
+func redactError(err error, config map[string]any, secrets map[string]bool) error {
+	msg := err.Error()
+	flat := flattenMap(config)
+	for key := range secrets {
+		v, ok := flat[key]
+		if !ok || v == nil {
+			continue
+		}
+		if value := fmt.Sprint(v); len(value) != 0 {
+			msg = strings.ReplaceAll(msg, value, redacted)
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
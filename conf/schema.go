@@ -0,0 +1,219 @@
+package conf
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schema returns a JSON Schema describing the config struct cfg points to, for editor
+// autocompletion and validation of config files, e.g. with the YAML language server.
+//
+// The properties are named after the yaml tags and typed after the Go fields. The `desc` tag
+// becomes the description, the `deprecated` tag marks the property as deprecated, fields
+// tagged `validate:"required"` are listed as required, and the non-zero values cfg holds are
+// emitted as defaults, except for secret fields. Unknown keys are rejected, except in maps
+// and structs with an inline map.
+func Schema(cfg any) ([]byte, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("cfg must be a non-nil pointer to a struct, got %T", cfg)
+	}
+	schema := schemaOf(v.Elem(), v.Elem().Type(), map[reflect.Type]bool{})
+	schema["$schema"] = schemaDialect
+	raw, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal schema")
+	}
+	return raw, nil
+}
+
+// schemaOf returns the schema of type t. v holds the current value used for defaults, it is
+// invalid when there is no value, e.g. for the elements of a slice.
+func schemaOf(v reflect.Value, t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if v.IsValid() {
+			if v.IsNil() {
+				v = reflect.Value{}
+			} else {
+				v = v.Elem()
+			}
+		}
+	}
+
+	switch {
+	case t == durationType:
+		return map[string]any{
+			"type":        []string{"string", "integer"},
+			"description": "duration such as 30s or 1h5m, integers are nanoseconds",
+		}
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.PointerTo(t).Implements(yamlUnmarshalerType):
+		// the type decodes itself, it may accept anything
+		return map[string]any{}
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": schemaOf(reflect.Value{}, t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(reflect.Value{}, t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			// recursive types are not expanded again
+			return map[string]any{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{},
+			"additionalProperties": false,
+		}
+		addProperties(schema, v, t, visiting)
+		return schema
+	}
+	// interfaces and other kinds accept anything
+	return map[string]any{}
+}
+
+// addProperties adds the fields of the struct type t to the properties of schema, inline
+// fields are flattened into it.
+func addProperties(schema map[string]any, v reflect.Value, t reflect.Type, visiting map[reflect.Type]bool) {
+	properties := schema["properties"].(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, inline, ok := yamlKey(sf)
+		if !ok {
+			continue
+		}
+		var fv reflect.Value
+		if v.IsValid() {
+			fv = v.Field(i)
+		}
+		if inline {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+				if fv.IsValid() && !fv.IsNil() {
+					fv = fv.Elem()
+				} else {
+					fv = reflect.Value{}
+				}
+			}
+			switch ft.Kind() {
+			case reflect.Struct:
+				addProperties(schema, fv, ft, visiting)
+			case reflect.Map:
+				schema["additionalProperties"] = schemaOf(reflect.Value{}, ft.Elem(), visiting)
+			}
+			continue
+		}
+
+		property := schemaOf(fv, sf.Type, visiting)
+		if desc := sf.Tag.Get("desc"); len(desc) != 0 {
+			property["description"] = desc
+		}
+		if msg, ok := sf.Tag.Lookup("deprecated"); ok {
+			property["deprecated"] = true
+			if len(msg) != 0 {
+				desc, _ := property["description"].(string)
+				property["description"] = strings.TrimSpace(desc + " Deprecated: " + msg)
+			}
+		}
+		if values := enumValues(sf); values != nil {
+			addEnum(property, sf.Type, values)
+		}
+		def, hasDefault := schemaDefault(fv)
+		// the schema is published to editors, the default of a secret would leak its value
+		if hasDefault && sf.Tag.Get("secret") != "true" {
+			property["default"] = def
+		}
+		if schemaRequired(sf, property, hasDefault) {
+			required, _ := schema["required"].([]string)
+			schema["required"] = append(required, name)
+		}
+		properties[name] = property
+	}
+}
+
+// schemaRequired reports whether the config file must set the field sf with the given
+// property, like Lint checks it: fields tagged `validate:"required"` without a default, and
+// nested structs with required fields unless they are pointers, which are optional as a whole.
+func schemaRequired(sf reflect.StructField, property map[string]any, hasDefault bool) bool {
+	if hasRule(sf, "required") {
+		return !hasDefault
+	}
+	_, nested := property["required"]
+	return nested && isNestedStruct(sf.Type) && sf.Type.Kind() != reflect.Pointer
+}
+
+// schemaDefault returns the value of a leaf field as it would be written in the config file,
+// ok is false for zero values and nested structs.
+func schemaDefault(v reflect.Value) (any, bool) {
+	if !v.IsValid() || v.IsZero() || isNestedStruct(v.Type()) {
+		return nil, false
+	}
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Type() == durationType {
+		return v.Interface().(time.Duration).String(), true
+	}
+	if m, ok := v.Interface().(interface{ MarshalText() ([]byte, error) }); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return nil, false
+		}
+		return string(text), true
+	}
+	return v.Interface(), true
+}
+
+// addEnum restricts the property of a field of type t to values, for slices the items are
+// restricted.
+func addEnum(property map[string]any, t reflect.Type, values []string) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if items, ok := property["items"].(map[string]any); ok && t.Kind() == reflect.Slice {
+		addEnum(items, t.Elem(), values)
+		return
+	}
+	enum := make([]any, 0, len(values))
+	for _, value := range values {
+		if v, err := typedValue(t, value); err == nil {
+			enum = append(enum, v)
+		} else {
+			enum = append(enum, value)
+		}
+	}
+	property["enum"] = enum
+}
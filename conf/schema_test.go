@@ -0,0 +1,66 @@
+package conf
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type schemaPG struct {
+	Host     string `yaml:"host" validate:"required"`
+	Port     int    `yaml:"port" validate:"required"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password" validate:"required" secret:"true"`
+}
+
+type schemaConfig struct {
+	Name  string    `yaml:"name" validate:"required" desc:"service name"`
+	Level string    `yaml:"level" enum:"debug,info"`
+	PG    schemaPG  `yaml:"pg"`
+	Proxy *schemaPG `yaml:"proxy"`
+	Extra struct {
+		Debug bool `yaml:"debug"`
+	} `yaml:"extra"`
+}
+
+func TestSchema(t *testing.T) {
+	raw, err := Schema(&schemaConfig{PG: schemaPG{Port: 5432, Password: "hunter2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "hunter2") {
+		t.Errorf("Schema() leaks the secret:\n%s", raw)
+	}
+	schema := map[string]any{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatal(err)
+	}
+	properties := schema["properties"].(map[string]any)
+	pg := properties["pg"].(map[string]any)
+	proxy := properties["proxy"].(map[string]any)
+	level := properties["level"].(map[string]any)
+	password, _ := pg["properties"].(map[string]any)["password"].(map[string]any)
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"required fields", schema["required"], []any{"name", "pg"}},
+		{"fields with a default are not required", pg["required"], []any{"host"}},
+		{"pointers are optional as a whole", proxy["required"], []any{"host", "port", "password"}},
+		{"default", pg["properties"].(map[string]any)["port"].(map[string]any)["default"], float64(5432)},
+		{"secret defaults are not emitted", password["default"], nil},
+		{"description", properties["name"].(map[string]any)["description"], "service name"},
+		{"enum", level["enum"], []any{"debug", "info"}},
+		{"no required fields", properties["extra"].(map[string]any)["required"], nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
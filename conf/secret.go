@@ -0,0 +1,121 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// readSecretFiles sets the fields of cfg tagged `secret:"true"` from the files named by
+// environment variables with a _FILE suffix, e.g. CFG_PG_PASSWORD_FILE=/run/secrets/pg sets
+// pg.password to the content of /run/secrets/pg without the trailing newline. config is the
+// parsed environment, where the variable shows up as pg.password.file. It must run before
+// squashEmbedded, so that both the full and the flattened name of a field in an embedded
+// struct are found, e.g. CFG_BASE_PASSWORD_FILE and CFG_PASSWORD_FILE. The full name wins if
+// both are set.
+func readSecretFiles(config map[string]any, cfg any) error {
+	fields, err := leafFields(cfg)
+	if err != nil {
+		// only structs have tags
+		return nil
+	}
+	for _, f := range fields {
+		if f.field.Tag.Get("secret") != "true" {
+			continue
+		}
+		var name any
+		found := false
+		for _, path := range [][]string{f.path, f.envPath} {
+			filePath := append(strings.Split(strings.ToLower(strings.Join(path, ".")), "."), "file")
+			v, ok := getPath(config, filePath)
+			if !ok {
+				continue
+			}
+			deletePath(config, filePath)
+			if !found {
+				name, found = v, true
+			}
+		}
+		if !found {
+			continue
+		}
+		raw, err := os.ReadFile(fmt.Sprint(name))
+		if err != nil {
+			return errors.Wrapf(err, "failed to read secret file of %s", strings.Join(f.path, "."))
+		}
+		setPath(config, f.path, strings.TrimRight(string(raw), "\r\n"))
+	}
+	return nil
+}
+
+// WithTTL makes a Watcher re-resolve the value at the dotted path at least every ttl, so a
+// rotated secret, e.g. a password read from a _FILE variable or a Source backed by a secret
+// manager, takes effect without a restart. Every refresh is a reload of the whole config and
+// fires the reload callbacks, so the shortest TTL and WithReloadInterval determine how often
+// the config is reloaded.
+//
+// Only Watch uses the TTLs, FetchConfig, NewStore and Init ignore them. Watch returns an error
+// if path is not a key of the config.
+func WithTTL(path string, ttl time.Duration) Option {
+	return func(o *options) {
+		if o.ttls == nil {
+			o.ttls = map[string]time.Duration{}
+		}
+		o.ttls[path] = ttl
+	}
+}
+
+// refreshInterval returns how often a Watcher reloads the config, 0 if it never does.
+func (o *options) refreshInterval() time.Duration {
+	interval := o.reloadInterval
+	for _, ttl := range o.ttls {
+		if ttl > 0 && (interval <= 0 || ttl < interval) {
+			interval = ttl
+		}
+	}
+	return interval
+}
+
+// checkTTLs returns an error if the path of a TTL is not a key of the config cfg points to.
+func checkTTLs(cfg any, ttls map[string]time.Duration) error {
+	paths := make([]string, 0, len(ttls))
+	for path := range ttls {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if len(path) == 0 || !hasKeyPath(reflect.TypeOf(cfg), strings.Split(path, ".")) {
+			return errors.Errorf("TTL path %q is not a config key", path)
+		}
+	}
+	return nil
+}
+
+// hasKeyPath reports whether the key path exists in a config of type t, as a field or as a key
+// below a map, an interface or a struct with an inline map.
+func hasKeyPath(t reflect.Type, path []string) bool {
+	for ; len(path) != 0; path = path[1:] {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Map, reflect.Interface:
+			return true
+		case reflect.Struct:
+			fields, inlineMap := structKeys(t)
+			sf, ok := fields[path[0]]
+			if !ok {
+				return inlineMap != nil
+			}
+			t = sf.Type
+		default:
+			return false
+		}
+	}
+	return true
+}
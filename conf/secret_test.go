@@ -0,0 +1,129 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SecretBase struct {
+	Password string `yaml:"password" secret:"true"`
+}
+
+type secretFileConfig struct {
+	SecretBase `yaml:"base"`
+	PG         struct {
+		Password string `yaml:"password" secret:"true"`
+	} `yaml:"pg"`
+}
+
+func TestReadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	full := writeSecret("full", "from-full\n")
+	flat := writeSecret("flat", "from-flat\r\n")
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		wantPG string
+		want   string
+	}{
+		{
+			name:   "nested field",
+			env:    map[string]string{"CFG_PG_PASSWORD_FILE": full},
+			wantPG: "from-full",
+		},
+		{
+			name:   "file wins over the value",
+			env:    map[string]string{"CFG_PG_PASSWORD": "plain", "CFG_PG_PASSWORD_FILE": full},
+			wantPG: "from-full",
+		},
+		{
+			name: "embedded field by its flattened name",
+			env:  map[string]string{"CFG_PASSWORD_FILE": flat},
+			want: "from-flat",
+		},
+		{
+			name: "embedded field by its full name",
+			env:  map[string]string{"CFG_BASE_PASSWORD_FILE": full},
+			want: "from-full",
+		},
+		{
+			name: "full name wins over the flattened name",
+			env:  map[string]string{"CFG_PASSWORD_FILE": flat, "CFG_BASE_PASSWORD_FILE": full},
+			want: "from-full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := &secretFileConfig{}
+			if err := FetchConfig("", "CFG", cfg); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.PG.Password != tt.wantPG {
+				t.Errorf("pg.password = %q, want %q", cfg.PG.Password, tt.wantPG)
+			}
+			if cfg.Password != tt.want {
+				t.Errorf("base.password = %q, want %q", cfg.Password, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSecretFilesMissing(t *testing.T) {
+	t.Setenv("CFG_PG_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if err := FetchConfig("", "CFG", &secretFileConfig{}); err == nil {
+		t.Fatal("FetchConfig() = nil, want an error for the missing secret file")
+	}
+}
+
+func TestCheckTTLs(t *testing.T) {
+	type ttlConfig struct {
+		SecretBase `yaml:",inline"`
+		PG         *struct {
+			Password string `yaml:"password" secret:"true"`
+		} `yaml:"pg"`
+		Vault   map[string]string `yaml:"vault"`
+		Created time.Time         `yaml:"created"`
+	}
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{path: "pg.password", ok: true},
+		{path: "pg", ok: true},
+		{path: "password", ok: true},
+		{path: "vault.token", ok: true},
+		{path: "pg.passwrod"},
+		{path: "base.password"},
+		{path: "created.wall"},
+		{path: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := checkTTLs(&ttlConfig{}, map[string]time.Duration{tt.path: time.Minute})
+			if (err == nil) != tt.ok {
+				t.Errorf("checkTTLs(%q) = %v, want ok %v", tt.path, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWatchUnknownTTLPath(t *testing.T) {
+	_, err := Watch[secretFileConfig]("", "TTLTEST", WithTTL("pg.passwrod", time.Minute))
+	if err == nil || !strings.Contains(err.Error(), `"pg.passwrod" is not a config key`) {
+		t.Fatalf("Watch() = %v, want an unknown path error", err)
+	}
+}
@@ -0,0 +1,82 @@
+package conf
+
+import "fmt"
+
+// Source provides one layer of config as a nested map, e.g. {"pg": {"host": "localhost"}}.
+type Source interface {
+	Load() (map[string]any, error)
+}
+
+// SourceFunc adapts an ordinary function to a Source.
+type SourceFunc func() (map[string]any, error)
+
+// Load calls f.
+func (f SourceFunc) Load() (map[string]any, error) {
+	return f()
+}
+
+// Static returns a Source providing a fixed nested map, e.g.
+// conf.Static(map[string]any{"pg": map[string]any{"host": "localhost"}}). It lets tests build
+// exact layered scenarios without touching the filesystem or the process environment.
+// The map is copied on every load, so it is never modified by the merge.
+func Static(config map[string]any) Source {
+	return namedSource{name: "static", Source: SourceFunc(func() (map[string]any, error) {
+		return config, nil
+	})}
+}
+
+// namedSource gives a Source a name used in traces and error messages.
+type namedSource struct {
+	Source
+	name string
+}
+
+func (s namedSource) String() string {
+	return s.name
+}
+
+// sourceName returns the name of s, which is its String method if it has one.
+func sourceName(s Source) string {
+	if st, ok := s.(fmt.Stringer); ok {
+		return st.String()
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// WithSource adds s as a config layer. Sources are merged on top of the config file and below
+// the environment variables, in the order they were added.
+func WithSource(s Source) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, s)
+	}
+}
+
+// copyMap deep copies m so that merging it into the config never modifies the source. Nested
+// maps with non-string keys, as produced by some decoders, are converted to map[string]any.
+func copyMap(m map[string]any) map[string]any {
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		c[k] = copyValue(v)
+	}
+	return c
+}
+
+func copyValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		return copyMap(t)
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = copyValue(v)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i := range t {
+			s[i] = copyValue(t[i])
+		}
+		return s
+	}
+	return v
+}
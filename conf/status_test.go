@@ -0,0 +1,86 @@
+package conf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type secretConfig struct {
+	PG struct {
+		Port     int    `yaml:"port"`
+		Password string `yaml:"password" secret:"true"`
+		// PIN is a secret that fails to decode itself, its value ends up in the error
+		PIN int `yaml:"pin" secret:"true"`
+	} `yaml:"pg"`
+	DB struct {
+		Password string `yaml:"password" secret:"true"`
+	} `yaml:"db"`
+}
+
+// brokenConfig is a config file and environment that fail to load and hold the secret
+// "hunter2".
+type brokenConfig struct {
+	file string
+	env  map[string]string
+}
+
+// newFailedStore returns a store whose last reload of the broken config failed.
+func newFailedStore(t *testing.T, broken brokenConfig) *Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "conf.yaml")
+	if err := os.WriteFile(path, []byte("pg:\n  port: 5432\n  password: hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(path, "SECRETTEST", &secretConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(broken.file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range broken.env {
+		t.Setenv(k, v)
+	}
+	if err := store.Reload(); err == nil {
+		t.Fatal("Reload() succeeded, want an error")
+	}
+	return store
+}
+
+var brokenSecretConfigs = map[string]brokenConfig{
+	"other field":  {file: "pg:\n  port: abc\n  password: hunter2\n"},
+	"secret field": {file: "pg:\n  port: 5432\n  password: hunter2\n  pin: hunter2\n"},
+	"merge error": {
+		file: "pg:\n  port: 5432\n",
+		env:  map[string]string{"SECRETTEST_DB_PASSWORD": "hunter2", "SECRETTEST_PG": "x"},
+	},
+}
+
+func TestStatusRedactsSecrets(t *testing.T) {
+	for name, broken := range brokenSecretConfigs {
+		t.Run(name, func(t *testing.T) {
+			store := newFailedStore(t, broken)
+			status := store.Status()
+			if status.Valid || len(status.Errors) == 0 {
+				t.Fatalf("Status() = %+v, want an error", status)
+			}
+			for layer, msg := range status.Errors {
+				if strings.Contains(msg, "hunter2") {
+					t.Errorf("error of %s leaks the secret: %s", layer, msg)
+				}
+			}
+			rec := httptest.NewRecorder()
+			HealthHandler(store).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("HealthHandler() code = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+			}
+			if body := rec.Body.String(); strings.Contains(body, "hunter2") || strings.Contains(body, "104 117 110") {
+				t.Errorf("HealthHandler() leaks the secret: %s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Store holds a config that can be reloaded while the application is running.
+// Every reload runs the whole pipeline of FetchConfig again and only replaces the current
+// config if it succeeds, so a broken config file never takes effect.
+type Store struct {
+	prefix     string
+	configPath string
+	opts       *options
+
+	// defaults is a copy of the value cfg pointed to before the initial load. Every
+	// reload starts from a fresh copy of it.
+	defaults reflect.Value
+
+	// reloadMu serializes reloads, so that the callbacks of overlapping reloads run in the
+	// order the configs are published and the last callback always sees the served config.
+	reloadMu sync.Mutex
+
+	mu     sync.RWMutex
+	cfg    any
+	merged *merged
+	// loadedAt is when the current config was loaded.
+	loadedAt time.Time
+	// lastReload is the outcome of the last reload, nil if it was never reloaded.
+	lastReload *ReloadStatus
+	// lastErr is the error of the last load attempt, nil if it succeeded.
+	lastErr error
+	// onReload are called with the new config after every successful reload.
+	onReload []func(cfg any)
+	// onRotate are called by dotted path when the value at the path changes in a reload.
+	onRotate map[string][]func(old, new string)
+}
+
+// ReloadStatus is the outcome of a call to Store.Reload.
+type ReloadStatus struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+// NewStore loads the config like FetchConfig and returns a Store holding it. cfg must be a
+// pointer to a struct, its current value is used as the defaults for every reload.
+func NewStore(configPath string, envPrefix string, cfg any, opts ...Option) (*Store, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil, errors.Errorf("cfg must be a non-nil pointer, got %T", cfg)
+	}
+	s := &Store{
+		prefix:     resolvePrefix(envPrefix),
+		configPath: configPath,
+		opts:       newOptions(opts),
+		defaults:   deepCopy(v),
+	}
+	m, err := fetch(context.Background(), s.prefix, s.configPath, cfg, s.opts)
+	if err != nil {
+		return nil, err
+	}
+	s.cfg = cfg
+	s.merged = m
+	s.loadedAt = time.Now()
+	return s, nil
+}
+
+// Get returns the current config, a pointer of the same type as the one passed to NewStore.
+// The returned config must not be modified, a reload replaces it with a new pointer.
+func (s *Store) Get() any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// OnReload registers fn to be called with the new config after every successful reload.
+func (s *Store) OnReload(fn func(cfg any)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReload = append(s.onReload, fn)
+}
+
+// OnSecretRotate registers fn to be called with the old and new value of the field at the
+// dotted path, typically one tagged `secret:"true"`, when a reload changes it. Components
+// holding live connections, e.g. DB pools or token clients, use it to rebuild their
+// credentials. A value that is not set is passed as "". Reloads are serialized, so the new
+// value of the last call is always the value the store serves.
+func (s *Store) OnSecretRotate(path string, fn func(old, new string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.onRotate == nil {
+		s.onRotate = map[string][]func(old, new string){}
+	}
+	s.onRotate[path] = append(s.onRotate[path], fn)
+}
+
+// Reload loads the config again. The current config is kept if loading fails.
+func (s *Store) Reload() error {
+	return s.ReloadContext(context.Background())
+}
+
+// ReloadContext is like Reload, the context is passed to the tracers registered with WithTracer.
+//
+// Reloads are serialized: a reload waits for the one in progress, including its callbacks, to
+// finish. Callbacks must therefore not reload the store themselves.
+func (s *Store) ReloadContext(ctx context.Context) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	ctx, end := s.opts.startStage(ctx, StageReload, s.configPath)
+	err := s.reload(ctx)
+	end(err)
+	status := &ReloadStatus{Time: time.Now()}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.lastReload = status
+	s.lastErr = err
+	s.mu.Unlock()
+	for _, obs := range s.opts.observers {
+		obs.ObserveReload(err)
+	}
+	return err
+}
+
+func (s *Store) reload(ctx context.Context) error {
+	cfg := deepCopy(s.defaults).Interface()
+	m, err := fetch(ctx, s.prefix, s.configPath, cfg, s.opts)
+	if err != nil {
+		return errors.Wrap(err, "failed to reload config")
+	}
+	s.mu.Lock()
+	old := s.merged
+	s.cfg = cfg
+	s.merged = m
+	s.loadedAt = time.Now()
+	onReload := s.onReload
+	onRotate := s.onRotate
+	s.mu.Unlock()
+	logChanges(s.opts.log(), old.config, m.config, cfg)
+	for _, fn := range onReload {
+		fn(cfg)
+	}
+	notifyRotations(onRotate, old.config, m.config)
+	return nil
+}
+
+// notifyRotations calls the callbacks of the paths whose value differs between old and new.
+func notifyRotations(onRotate map[string][]func(old, new string), old, new map[string]any) {
+	if len(onRotate) == 0 {
+		return
+	}
+	o, n := flattenMap(old), flattenMap(new)
+	for path, fns := range onRotate {
+		ov, nv := stringValue(o, path), stringValue(n, path)
+		if ov == nv {
+			continue
+		}
+		for _, fn := range fns {
+			fn(ov, nv)
+		}
+	}
+}
+
+// stringValue returns the value at key in the flattened config as a string, "" if it is not set.
+func stringValue(flat map[string]any, key string) string {
+	v, ok := flat[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// deepCopy returns a copy of v that shares no pointers, slices or maps with it, so that
+// decoding into the copy never modifies the original.
+func deepCopy(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	copyInto(c, v)
+	return c
+}
+
+func copyInto(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.New(src.Type().Elem()))
+		copyInto(dst.Elem(), src.Elem())
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(deepCopy(src.Elem()))
+	case reflect.Struct:
+		// unexported fields cannot be deep copied, they are kept as they are
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyInto(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyInto(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,106 @@
+package conf
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type rotateConfig struct {
+	DB struct {
+		Password string `yaml:"password" secret:"true"`
+		Host     string `yaml:"host"`
+	} `yaml:"db"`
+}
+
+// rotatingSource returns a source whose password changes on every load, its host only if
+// changeHost is set. Loads take a varying time, so that overlapping reloads finish out of
+// order.
+func rotatingSource(changeHost *atomic.Bool) Source {
+	var n atomic.Int64
+	return SourceFunc(func() (map[string]any, error) {
+		v := n.Add(1)
+		time.Sleep(time.Duration(v%3) * time.Millisecond)
+		db := map[string]any{"password": "p" + strconv.FormatInt(v, 10), "host": "db"}
+		if changeHost.Load() {
+			db["host"] = "db" + strconv.FormatInt(v, 10)
+		}
+		return map[string]any{"db": db}, nil
+	})
+}
+
+func TestOnSecretRotate(t *testing.T) {
+	changeHost := &atomic.Bool{}
+	store, err := NewStore("", "ROTATETEST", &rotateConfig{}, WithSource(rotatingSource(changeHost)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type rotation struct{ old, new string }
+	var passwords, hosts []rotation
+	store.OnSecretRotate("db.password", func(old, new string) {
+		passwords = append(passwords, rotation{old, new})
+	})
+	store.OnSecretRotate("db.host", func(old, new string) {
+		hosts = append(hosts, rotation{old, new})
+	})
+	store.OnSecretRotate("db.missing", func(old, new string) {
+		t.Errorf("callback of an unset path called with %q, %q", old, new)
+	})
+
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []rotation{{"p1", "p2"}}; len(passwords) != 1 || passwords[0] != want[0] {
+		t.Errorf("password rotations = %v, want %v", passwords, want)
+	}
+	if len(hosts) != 0 {
+		t.Errorf("host rotations = %v, want none for an unchanged value", hosts)
+	}
+	changeHost.Store(true)
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if want := (rotation{"db", "db3"}); len(hosts) != 1 || hosts[0] != want {
+		t.Errorf("host rotations = %v, want [%v]", hosts, want)
+	}
+}
+
+func TestOnSecretRotateConcurrentReloads(t *testing.T) {
+	store, err := NewStore("", "ROTATETEST", &rotateConfig{}, WithSource(rotatingSource(&atomic.Bool{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reload callbacks run before the rotation callbacks, taking a varying time gives
+	// overlapping reloads the chance to notify out of order
+	store.OnReload(func(cfg any) {
+		password := cfg.(*rotateConfig).DB.Password
+		time.Sleep(time.Duration(password[len(password)-1]%3) * time.Millisecond)
+	})
+	var mu sync.Mutex
+	var last string
+	store.OnSecretRotate("db.password", func(old, new string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if old != last && len(last) != 0 {
+			t.Errorf("rotation from %q, but the last rotation was to %q", old, last)
+		}
+		last = new
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Reload(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if served := store.Get().(*rotateConfig).DB.Password; last != served {
+		t.Errorf("last rotation to %q, but the store serves %q", last, served)
+	}
+}
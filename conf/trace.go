@@ -0,0 +1,59 @@
+package conf
+
+import "context"
+
+// Stage is a step of the load pipeline reported to a Tracer.
+type Stage string
+
+const (
+	// StageLoad is the whole load pipeline, all other stages except StageReload are nested in it.
+	StageLoad Stage = "load"
+	// StageReload is a call to Store.Reload, it wraps StageLoad.
+	StageReload Stage = "reload"
+	// StageFile is reading and parsing the config file.
+	StageFile Stage = "file"
+	// StageSource is loading a Source, including flags and overrides.
+	StageSource Stage = "source"
+	// StageEnv is reading the environment variables and merging them into the config.
+	StageEnv Stage = "env"
+	// StageTransform is running a transform registered with WithTransform, the name is its
+	// index.
+	StageTransform Stage = "transform"
+	// StageDecode is decoding the merged config into the struct.
+	StageDecode Stage = "decode"
+	// StageValidate is calling Validate on configs implementing Validator, checking the
+	// `validate` tags, named "tags", or a TreeValidator, named "tree".
+	StageValidate Stage = "validate"
+	// StageHook is running a hook registered with WithPostLoadHook, the name is its index.
+	StageHook Stage = "hook"
+)
+
+// Tracer traces the stages of the load pipeline, e.g. to find slow config backends.
+// See the confotel package for an OpenTelemetry implementation.
+type Tracer interface {
+	// StartStage is called when a stage starts. name identifies what is loaded, e.g. the path
+	// of the config file, and may be empty. The returned context is passed to the nested
+	// stages and the returned function is called with the outcome when the stage ends.
+	StartStage(ctx context.Context, stage Stage, name string) (context.Context, func(err error))
+}
+
+// WithTracer registers t to trace the stages of the load pipeline.
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracers = append(o.tracers, t)
+	}
+}
+
+func (o *options) startStage(ctx context.Context, stage Stage, name string) (context.Context, func(error)) {
+	ends := make([]func(error), 0, len(o.tracers))
+	for _, t := range o.tracers {
+		var end func(error)
+		ctx, end = t.StartStage(ctx, stage, name)
+		ends = append(ends, end)
+	}
+	return ctx, func(err error) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](err)
+		}
+	}
+}
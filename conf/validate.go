@@ -0,0 +1,218 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// rule is a single rule of a `validate` tag, e.g. required_if=TLS.Enabled true.
+type rule struct {
+	name  string
+	param string
+}
+
+// parseRules returns the comma separated rules of the `validate` tag of f.
+func parseRules(f field) []rule {
+	tag := f.field.Tag.Get("validate")
+	if len(tag) == 0 {
+		return nil
+	}
+	rules := []rule{}
+	for _, r := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(r), "=")
+		if len(name) != 0 {
+			rules = append(rules, rule{name: name, param: strings.TrimSpace(param)})
+		}
+	}
+	return rules
+}
+
+// enumValues returns the allowed values of the field from its `enum` tag, e.g.
+// `enum:"debug,info,warn,error"`, nil if it has none.
+func enumValues(sf reflect.StructField) []string {
+	tag := sf.Tag.Get("enum")
+	if len(tag) == 0 {
+		return nil
+	}
+	values := strings.Split(tag, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// checkEnum returns an error if v, or an element of it if it is a slice, is not one of values.
+func checkEnum(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			if err := checkEnum(v.Index(i), values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	got := fmt.Sprint(v.Interface())
+	for _, value := range values {
+		if got == value {
+			return nil
+		}
+	}
+	return errors.Errorf("%q is not one of %s", got, strings.Join(values, ", "))
+}
+
+// validateTags checks the `validate` tags of the fields of cfg after it was decoded from
+// config, the merged config. The rules are:
+//
+//   - required: the field must be set. Fields of a nil pointer to a struct are exempt, the
+//     struct is optional as a whole.
+//   - required_if=Field value ...: the field must be set if all the listed fields are set to
+//     their values, e.g. `validate:"required_if=Enabled true"`.
+//   - required_with=Field ...: the field must be set if any of the listed fields is set.
+//   - exclusive=group: at most one of the fields in the group may be set.
+//
+// Other rules, e.g. those of go-playground/validator, are ignored, so structs already tagged
+// for it keep working. The rule names and the meaning of Field are the same as there: Field
+// is the dotted path of the other field relative to the struct holding the field, made of
+// either the Go field names or the yaml keys, e.g. Enabled for a sibling or TLS.Enabled for
+// a field of the nested struct TLS.
+//
+// Fields with an `enum` tag, e.g. `enum:"debug,info,warn,error"`, must be one of the listed
+// values if they are set.
+//
+// Like in go-playground/validator, a field is set if its value is not the zero value, whether
+// it comes from a layer or is a default. An explicit zero value, e.g. enabled: false or
+// name: "", does not set it; pointer fields, e.g. *bool, are set by any value.
+//
+// The enum values are checked if the key is in the merged config or the value is not the zero
+// value, so that an explicit "" is rejected unless it is listed.
+func validateTags(cfg any, config map[string]any) error {
+	fields, err := leafFields(cfg)
+	if err != nil {
+		// only structs have tags
+		return nil
+	}
+	flat := flattenMap(config)
+	isSet := func(f field) bool {
+		return !f.detached && !f.value.IsZero()
+	}
+	isPresent := func(f field) bool {
+		if f.detached {
+			return false
+		}
+		_, ok := flat[strings.Join(f.path, ".")]
+		return ok || !f.value.IsZero()
+	}
+	byPath := map[string]field{}
+	for _, f := range fields {
+		byPath[strings.Join(f.path, ".")] = f
+		byPath[strings.Join(f.goPath, ".")] = f
+	}
+
+	problems := []string{}
+	groups := map[string][]field{}
+	for _, f := range fields {
+		key := strings.Join(f.path, ".")
+		if values := enumValues(f.field); values != nil && isPresent(f) {
+			if err := checkEnum(f.value, values); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", key, err))
+			}
+		}
+		for _, r := range parseRules(f) {
+			switch r.name {
+			case "required":
+				if !f.detached && !isSet(f) {
+					problems = append(problems, fmt.Sprintf("%s is required", key))
+				}
+			case "required_if", "required_with":
+				if isSet(f) {
+					continue
+				}
+				refs := strings.Fields(r.param)
+				step := 1
+				if r.name == "required_if" {
+					step = 2
+					if len(refs)%2 != 0 {
+						problems = append(problems, fmt.Sprintf("%s: required_if needs pairs of a field and a value, got %q", key, r.param))
+						continue
+					}
+				}
+				conditions := []string{}
+				unknown := false
+				for i := 0; i < len(refs); i += step {
+					other, ok := lookupSibling(byPath, f, refs[i])
+					if !ok {
+						problems = append(problems, fmt.Sprintf("%s: %s refers to unknown field %q", key, r.name, refs[i]))
+						unknown = true
+						break
+					}
+					otherKey := strings.Join(other.path, ".")
+					if r.name == "required_with" {
+						if isSet(other) {
+							conditions = append(conditions, otherKey+" is set")
+						}
+						continue
+					}
+					// compared even if unset, so that e.g. Enabled false matches the zero value
+					v := reflect.Indirect(other.value)
+					if other.detached || !v.IsValid() || fmt.Sprint(v.Interface()) != refs[i+1] {
+						conditions = nil
+						break
+					}
+					conditions = append(conditions, otherKey+" is "+refs[i+1])
+				}
+				if unknown || len(conditions) == 0 {
+					continue
+				}
+				if r.name == "required_with" {
+					// any of the fields requires it, the first one is enough to explain why
+					conditions = conditions[:1]
+				}
+				problems = append(problems, fmt.Sprintf("%s is required when %s", key, strings.Join(conditions, " and ")))
+			case "exclusive":
+				groups[r.param] = append(groups[r.param], f)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		set := []string{}
+		for _, f := range groups[name] {
+			if isSet(f) {
+				set = append(set, strings.Join(f.path, "."))
+			}
+		}
+		if len(set) > 1 {
+			problems = append(problems, fmt.Sprintf("%s are mutually exclusive", strings.Join(set, ", ")))
+		}
+	}
+	if len(problems) != 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// lookupSibling returns the field at the dotted path ref relative to the struct holding f.
+func lookupSibling(byPath map[string]field, f field, ref string) (field, bool) {
+	for _, parent := range [][]string{f.goPath, f.path} {
+		path := append(append([]string{}, parent[:len(parent)-1]...), ref)
+		if other, ok := byPath[strings.Join(path, ".")]; ok {
+			return other, true
+		}
+	}
+	return field{}, false
+}
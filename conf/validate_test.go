@@ -0,0 +1,178 @@
+package conf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type validateTLS struct {
+	Enabled bool   `yaml:"enabled"`
+	Mode    string `yaml:"mode"`
+	Cert    string `yaml:"cert" validate:"required_if=Enabled true"`
+	Key     string `yaml:"key" validate:"required_with=Cert"`
+	CA      string `yaml:"ca" validate:"required_if=Enabled true Mode mutual"`
+	Verify  *bool  `yaml:"verify"`
+	Reason  string `yaml:"reason" validate:"required_if=Verify false"`
+}
+
+type validateProxy struct {
+	URL string `yaml:"url" validate:"required"`
+}
+
+type validateConfig struct {
+	Name     string         `yaml:"name" validate:"required"`
+	Workers  int            `yaml:"workers" validate:"min=1,max=10"`
+	Level    string         `yaml:"level" enum:"debug,info"`
+	TLS      validateTLS    `yaml:"tls"`
+	Proxy    *validateProxy `yaml:"proxy"`
+	Token    string         `yaml:"token" validate:"exclusive=auth"`
+	Password string         `yaml:"password" validate:"exclusive=auth,required_with=TLS.Enabled"`
+}
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+		want   []string
+	}{
+		{
+			name:   "valid",
+			config: map[string]any{"name": "a", "level": "info"},
+		},
+		{
+			name:   "required",
+			config: map[string]any{},
+			want:   []string{"name is required"},
+		},
+		{
+			name:   "rules of other validators are ignored",
+			config: map[string]any{"name": "a", "workers": 100},
+		},
+		{
+			name:   "enum",
+			config: map[string]any{"name": "a", "level": "trace"},
+			want:   []string{`level: "trace" is not one of debug, info`},
+		},
+		{
+			name:   "required_if refers to a sibling",
+			config: map[string]any{"name": "a", "password": "p", "tls": map[string]any{"enabled": true}},
+			want:   []string{"tls.cert is required when tls.enabled is true"},
+		},
+		{
+			name: "required_if needs all conditions",
+			config: map[string]any{"name": "a", "password": "p",
+				"tls": map[string]any{"enabled": true, "mode": "mutual", "cert": "c", "key": "k"}},
+			want: []string{"tls.ca is required when tls.enabled is true and tls.mode is mutual"},
+		},
+		{
+			name:   "required_with",
+			config: map[string]any{"name": "a", "tls": map[string]any{"cert": "c"}},
+			want:   []string{"tls.key is required when tls.cert is set"},
+		},
+		{
+			name:   "required_with refers to a nested field",
+			config: map[string]any{"name": "a", "tls": map[string]any{"enabled": true, "cert": "c", "key": "k"}},
+			want:   []string{"password is required when tls.enabled is set"},
+		},
+		{
+			name:   "an explicit false does not set a field",
+			config: map[string]any{"name": "a", "tls": map[string]any{"enabled": false}},
+		},
+		{
+			name:   "an explicit empty string does not satisfy required",
+			config: map[string]any{"name": ""},
+			want:   []string{"name is required"},
+		},
+		{
+			name:   "required_if matches an explicit false",
+			config: map[string]any{"name": "a", "tls": map[string]any{"verify": false}},
+			want:   []string{"tls.reason is required when tls.verify is false"},
+		},
+		{
+			name:   "required_if does not match a nil pointer",
+			config: map[string]any{"name": "a", "tls": map[string]any{"verify": nil}},
+		},
+		{
+			name:   "an explicit empty string is checked against the enum",
+			config: map[string]any{"name": "a", "level": ""},
+			want:   []string{`level: "" is not one of debug, info`},
+		},
+		{
+			name:   "fields of a nil pointer are exempt",
+			config: map[string]any{"name": "a"},
+		},
+		{
+			name:   "fields of a set pointer are checked",
+			config: map[string]any{"name": "a", "proxy": map[string]any{}},
+			want:   []string{"proxy.url is required"},
+		},
+		{
+			name:   "exclusive",
+			config: map[string]any{"name": "a", "token": "t", "password": "p"},
+			want:   []string{"token, password are mutually exclusive"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &validateConfig{}
+			if err := decodeConfigMap(tt.config, cfg); err != nil {
+				t.Fatal(err)
+			}
+			err := validateTags(cfg, tt.config)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("validateTags() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateTags() = nil, want %q", tt.want)
+			}
+			if got := strings.Split(err.Error(), "; "); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("validateTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTagsUnknownField(t *testing.T) {
+	cfg := &struct {
+		A string `yaml:"a" validate:"required_with=Missing"`
+	}{}
+	err := validateTags(cfg, map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), `refers to unknown field "Missing"`) {
+		t.Fatalf("validateTags() = %v, want an unknown field error", err)
+	}
+}
+
+func TestWithTreeValidator(t *testing.T) {
+	cfg := &struct {
+		Name string `yaml:"name"`
+	}{}
+	var seen map[string]any
+	validate := TreeValidatorFunc(func(tree map[string]any) error {
+		seen = tree
+		tree["name"] = "changed"
+		if _, ok := tree["legacy"]; ok {
+			return errors.New("legacy was removed")
+		}
+		return nil
+	})
+
+	err := FetchConfig("", "TREETEST", cfg, WithOverrides(map[string]string{"name": "a", "unknown": "b"}), WithTreeValidator(validate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen["unknown"] != "b" {
+		t.Errorf("tree = %v, want the keys the struct does not have", seen)
+	}
+	if cfg.Name != "a" {
+		t.Errorf("name = %q, the validator must not change the config", cfg.Name)
+	}
+
+	err = FetchConfig("", "TREETEST", cfg, WithOverride("legacy", "x"), WithTreeValidator(validate))
+	if err == nil || !strings.Contains(err.Error(), "legacy was removed") {
+		t.Fatalf("FetchConfig() = %v, want the validator error", err)
+	}
+}
@@ -0,0 +1,256 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Tree is a merged config tree exposed through a Viper-like API. It eases migrating code
+// that reads its config with viper.Get* calls onto conf, one call site at a time.
+//
+// Keys are dotted paths, e.g. "pg.host", and are matched case-insensitively like in Viper.
+type Tree struct {
+	config map[string]any
+}
+
+// FetchTree reads the config the same way as FetchConfig, but returns the merged tree
+// instead of unmarshalling it into a struct.
+func FetchTree(configPath string, envPrefix string, opts ...Option) (*Tree, error) {
+	prefix := resolvePrefix(envPrefix)
+	m, err := readConfigMap(context.Background(), prefix, configPath, nil, newOptions(opts))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read and patch config")
+	}
+	return &Tree{config: m.config}, nil
+}
+
+// Get returns the value at the dotted key, or nil if it is not set.
+func (t *Tree) Get(key string) any {
+	v, _ := t.lookup(key)
+	return v
+}
+
+// IsSet reports whether the key is set in the tree.
+func (t *Tree) IsSet(key string) bool {
+	_, ok := t.lookup(key)
+	return ok
+}
+
+// GetString returns the value at key as a string.
+func (t *Tree) GetString(key string) string {
+	v, ok := t.lookup(key)
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// GetBool returns the value at key as a bool, or false if it cannot be converted.
+func (t *Tree) GetBool(key string) bool {
+	switch v := t.Get(key).(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	case int:
+		return v != 0
+	}
+	return false
+}
+
+// GetInt returns the value at key as an int, or 0 if it cannot be converted.
+func (t *Tree) GetInt(key string) int {
+	return int(t.GetInt64(key))
+}
+
+// GetInt64 returns the value at key as an int64, or 0 if it cannot be converted.
+func (t *Tree) GetInt64(key string) int64 {
+	switch v := t.Get(key).(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case uint64:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	}
+	return 0
+}
+
+// GetFloat64 returns the value at key as a float64, or 0 if it cannot be converted.
+func (t *Tree) GetFloat64(key string) float64 {
+	switch v := t.Get(key).(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+// GetDuration returns the value at key as a time.Duration. Strings are parsed with
+// time.ParseDuration and integers are taken as nanoseconds.
+func (t *Tree) GetDuration(key string) time.Duration {
+	switch v := t.Get(key).(type) {
+	case string:
+		d, _ := time.ParseDuration(v)
+		return d
+	case int:
+		return time.Duration(v)
+	case int64:
+		return time.Duration(v)
+	}
+	return 0
+}
+
+// GetStringSlice returns the value at key as a slice of strings. A string value is split
+// on commas.
+func (t *Tree) GetStringSlice(key string) []string {
+	switch v := t.Get(key).(type) {
+	case []any:
+		s := make([]string, 0, len(v))
+		for _, item := range v {
+			s = append(s, fmt.Sprint(item))
+		}
+		return s
+	case string:
+		if len(v) == 0 {
+			return []string{}
+		}
+		return strings.Split(v, ",")
+	}
+	return nil
+}
+
+// GetStringMap returns a copy of the map at key, or nil if the value is not a map.
+func (t *Tree) GetStringMap(key string) map[string]any {
+	m, ok := t.Get(key).(map[string]any)
+	if !ok {
+		return nil
+	}
+	return copyMap(m)
+}
+
+// GetStringMapString returns the map at key with all values converted to strings.
+func (t *Tree) GetStringMapString(key string) map[string]string {
+	m, ok := t.Get(key).(map[string]any)
+	if !ok {
+		return nil
+	}
+	s := make(map[string]string, len(m))
+	for k, v := range m {
+		s[k] = fmt.Sprint(v)
+	}
+	return s
+}
+
+// Sub returns the subtree at key, or nil if the value at key is not a map.
+func (t *Tree) Sub(key string) *Tree {
+	m, ok := t.Get(key).(map[string]any)
+	if !ok {
+		return nil
+	}
+	return &Tree{config: m}
+}
+
+// AllKeys returns the dotted paths of all leaf values in the tree, sorted.
+func (t *Tree) AllKeys() []string {
+	keys := []string{}
+	for k := range flattenMap(t.config) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AllSettings returns a copy of the whole tree.
+func (t *Tree) AllSettings() map[string]any {
+	return copyMap(t.config)
+}
+
+// Load implements Source, so a Tree can be layered into another FetchConfig call.
+func (t *Tree) Load() (map[string]any, error) {
+	return t.AllSettings(), nil
+}
+
+// Unmarshal decodes the whole tree into cfg.
+func (t *Tree) Unmarshal(cfg any) error {
+	return unmarshalMap(t.config, cfg)
+}
+
+// UnmarshalKey decodes the subtree or value at key into cfg.
+func (t *Tree) UnmarshalKey(key string, cfg any) error {
+	v, ok := t.lookup(key)
+	if !ok {
+		return errors.Errorf("key %s is not set", key)
+	}
+	return unmarshalMap(v, cfg)
+}
+
+func (t *Tree) lookup(key string) (any, bool) {
+	var cur any = t.config
+	for _, k := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur, ok = lookupKey(m, k)
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// lookupKey looks up k in m, falling back to a case-insensitive match.
+func lookupKey(m map[string]any, k string) (any, bool) {
+	if v, ok := m[k]; ok {
+		return v, true
+	}
+	for mk, v := range m {
+		if strings.EqualFold(mk, k) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func unmarshalMap(v any, cfg any) error {
+	raw, err := yaml.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "yaml marshal error")
+	}
+	return marshallRawYAML(raw, cfg)
+}
+
+// SettingsProvider is implemented by *viper.Viper.
+type SettingsProvider interface {
+	AllSettings() map[string]any
+}
+
+// ViperSource returns a Source reading all settings of a viper instance, so that an existing
+// viper setup can be layered into FetchConfig while migrating away from it.
+func ViperSource(v SettingsProvider) Source {
+	return namedSource{name: "viper", Source: SourceFunc(func() (map[string]any, error) {
+		return v.AllSettings(), nil
+	})}
+}
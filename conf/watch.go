@@ -0,0 +1,103 @@
+package conf
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Watcher holds the config of type T and keeps it up to date. It combines loading,
+// validating and reloading in one typed object on top of a Store.
+type Watcher[T any] struct {
+	store   *Store
+	current atomic.Pointer[T]
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// WithReloadInterval makes a Watcher reload the config every d. Failed reloads keep the
+// current config and are reported through the Store's status, observers and logger.
+func WithReloadInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.reloadInterval = d
+	}
+}
+
+// Watch loads the config of type T like NewStore and returns a Watcher holding it. With
+// WithReloadInterval or WithTTL the config is reloaded periodically until Close is called. If *T
+// implements Validator, every load is validated and invalid configs never take effect.
+//
+// Example:
+//
+//	w, err := conf.Watch[Config]("conf.yaml", "MYAPP", conf.WithReloadInterval(time.Minute))
+//	if err != nil {
+//		panic(err)
+//	}
+//	defer w.Close()
+//	cfg := w.Get()
+func Watch[T any](configPath string, envPrefix string, opts ...Option) (*Watcher[T], error) {
+	if err := checkTTLs(new(T), newOptions(opts).ttls); err != nil {
+		return nil, err
+	}
+	store, err := NewStore(configPath, envPrefix, new(T), opts...)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher[T]{
+		store: store,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	w.current.Store(store.Get().(*T))
+	store.OnReload(func(cfg any) {
+		w.current.Store(cfg.(*T))
+	})
+	go w.run(store.opts.refreshInterval())
+	return w, nil
+}
+
+// Get returns the current snapshot of the config without locking. Every reload creates a
+// new snapshot instead of modifying the current one, so a snapshot never changes once
+// returned. It must not be modified by the caller either.
+func (w *Watcher[T]) Get() *T {
+	return w.current.Load()
+}
+
+// Reload reloads the config immediately, see Store.Reload.
+func (w *Watcher[T]) Reload() error {
+	return w.store.Reload()
+}
+
+// Store returns the Store behind the watcher, e.g. to serve its status.
+func (w *Watcher[T]) Store() *Store {
+	return w.store
+}
+
+// Close stops the periodic reloads. The last snapshot stays available through Get.
+func (w *Watcher[T]) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+func (w *Watcher[T]) run(interval time.Duration) {
+	defer close(w.done)
+	if interval <= 0 {
+		<-w.stop
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			// a failed reload keeps the current config and is reported by the store
+			_ = w.store.Reload()
+		}
+	}
+}
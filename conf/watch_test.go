@@ -0,0 +1,99 @@
+package conf
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type watchConfig struct {
+	V int `yaml:"v"`
+}
+
+// countingSource returns a source setting v to the number of times it was loaded. Loads
+// take a varying time, so that overlapping reloads finish out of order.
+func countingSource() Source {
+	var n atomic.Int64
+	return SourceFunc(func() (map[string]any, error) {
+		v := n.Add(1)
+		time.Sleep(time.Duration(v%3) * time.Millisecond)
+		return map[string]any{"v": int(v)}, nil
+	})
+}
+
+func TestWatch(t *testing.T) {
+	w, err := Watch[watchConfig]("", "WATCHTEST", WithSource(countingSource()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if got := w.Get().V; got != 1 {
+		t.Fatalf("Get().V = %d, want 1", got)
+	}
+	first := w.Get()
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Get().V; got != 2 {
+		t.Errorf("Get().V = %d after a reload, want 2", got)
+	}
+	if first.V != 1 {
+		t.Errorf("a reload changed the previous snapshot to %d", first.V)
+	}
+}
+
+func TestWatchReloadInterval(t *testing.T) {
+	w, err := Watch[watchConfig]("", "WATCHTEST", WithSource(countingSource()), WithReloadInterval(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Get().V < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Get().V = %d, want periodic reloads", w.Get().V)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWatchConcurrentReloads(t *testing.T) {
+	w, err := Watch[watchConfig]("", "WATCHTEST", WithSource(countingSource()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var mu sync.Mutex
+	var order []int
+	w.Store().OnReload(func(cfg any) {
+		mu.Lock()
+		order = append(order, cfg.(*watchConfig).V)
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.Reload(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	served := w.Store().Get().(*watchConfig).V
+	if got := w.Get().V; got != served {
+		t.Errorf("Get().V = %d, but the store serves %d", got, served)
+	}
+	for i := 1; i < len(order); i++ {
+		if order[i] < order[i-1] {
+			t.Fatalf("callbacks ran out of order: %v", order)
+		}
+	}
+	if last := order[len(order)-1]; last != served {
+		t.Errorf("last callback got %d, but the store serves %d", last, served)
+	}
+}
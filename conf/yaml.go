@@ -0,0 +1,117 @@
+package conf
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultYAMLMaxDepth = 100
+	defaultYAMLMaxNodes = 1 << 20
+)
+
+// WithYAMLLimits limits the config file to maxDepth levels of nesting and maxNodes nodes after
+// all aliases are expanded. A file exceeding a limit fails to load instead of exhausting the
+// memory, e.g. a "billion laughs" file that expands a few lines of aliases exponentially.
+// Values <= 0 keep the defaults of 100 levels and 1048576 nodes.
+//
+// Anchors, aliases and merge keys ("<<") within the limits are always expanded, so the
+// config behaves as if the file had been written out in full.
+func WithYAMLLimits(maxDepth, maxNodes int) Option {
+	return func(o *options) {
+		o.yamlMaxDepth = maxDepth
+		o.yamlMaxNodes = maxNodes
+	}
+}
+
+type yamlLimits struct {
+	maxDepth int
+	maxNodes int
+}
+
+func (o *options) yamlLimits() yamlLimits {
+	l := yamlLimits{maxDepth: defaultYAMLMaxDepth, maxNodes: defaultYAMLMaxNodes}
+	if o.yamlMaxDepth > 0 {
+		l.maxDepth = o.yamlMaxDepth
+	}
+	if o.yamlMaxNodes > 0 {
+		l.maxNodes = o.yamlMaxNodes
+	}
+	return l
+}
+
+// unmarshalYAMLMap decodes raw into a map with all aliases expanded, after checking that the
+// expanded document stays within limits.
+func unmarshalYAMLMap(raw []byte, limits yamlLimits) (map[string]any, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if err := limits.check(&doc); err != nil {
+		return nil, err
+	}
+	config := map[string]any{}
+	if len(doc.Content) == 0 {
+		return config, nil
+	}
+	if err := doc.Decode(&config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// check returns an error if the document n exceeds the limits once its aliases are expanded,
+// or if an alias refers to a node containing it.
+func (l yamlLimits) check(n *yaml.Node) error {
+	w := &yamlWalker{expanded: map[*yaml.Node]yamlSize{}, visiting: map[*yaml.Node]bool{}}
+	_, err := w.size(n, l)
+	return err
+}
+
+// yamlSize is the size of a node with all aliases expanded.
+type yamlSize struct {
+	nodes int
+	depth int
+}
+
+type yamlWalker struct {
+	// expanded memoizes the size of every node, so that aliases to the same anchor are only
+	// walked once. Otherwise checking an alias bomb would take as long as expanding it.
+	expanded map[*yaml.Node]yamlSize
+	visiting map[*yaml.Node]bool
+}
+
+func (w *yamlWalker) size(n *yaml.Node, l yamlLimits) (yamlSize, error) {
+	if s, ok := w.expanded[n]; ok {
+		return s, nil
+	}
+	if w.visiting[n] {
+		return yamlSize{}, errors.Errorf("yaml anchor at line %d contains an alias to itself", n.Line)
+	}
+	w.visiting[n] = true
+	defer delete(w.visiting, n)
+
+	s := yamlSize{nodes: 1}
+	children := n.Content
+	if n.Kind == yaml.AliasNode {
+		children = []*yaml.Node{n.Alias}
+	}
+	for _, child := range children {
+		cs, err := w.size(child, l)
+		if err != nil {
+			return yamlSize{}, err
+		}
+		s.nodes += cs.nodes
+		if cs.depth+1 > s.depth {
+			s.depth = cs.depth + 1
+		}
+		if s.nodes > l.maxNodes {
+			return yamlSize{}, errors.Errorf("yaml document expands to more than %d nodes", l.maxNodes)
+		}
+		if s.depth > l.maxDepth {
+			return yamlSize{}, errors.Errorf("yaml document is nested more than %d levels deep", l.maxDepth)
+		}
+	}
+	w.expanded[n] = s
+	return s, nil
+}
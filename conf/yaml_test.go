@@ -0,0 +1,113 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// yamlBomb returns a "billion laughs" document of the given number of levels, each one
+// referring to the previous one ten times.
+func yamlBomb(levels int) string {
+	var b strings.Builder
+	b.WriteString("a0: &a0 [lol, lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for i := 1; i < levels; i++ {
+		prev := "*a" + string(rune('0'+i-1))
+		name := "a" + string(rune('0'+i))
+		b.WriteString(name + ": &" + name + " [" + strings.Repeat(prev+", ", 9) + prev + "]\n")
+	}
+	return b.String()
+}
+
+func TestUnmarshalYAMLMap(t *testing.T) {
+	defaults := newOptions(nil).yamlLimits()
+	tests := []struct {
+		name    string
+		raw     string
+		limits  yamlLimits
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name:   "empty document",
+			raw:    "",
+			limits: defaults,
+			want:   map[string]any{},
+		},
+		{
+			name:   "aliases and merge keys are expanded",
+			raw:    "base: &base\n  host: localhost\n  port: 5432\nprimary:\n  <<: *base\n  port: 5433\nreplica: *base\n",
+			limits: defaults,
+			want: map[string]any{
+				"base":    map[string]any{"host": "localhost", "port": 5432},
+				"primary": map[string]any{"host": "localhost", "port": 5433},
+				"replica": map[string]any{"host": "localhost", "port": 5432},
+			},
+		},
+		{
+			name:    "billion laughs",
+			raw:     yamlBomb(9),
+			limits:  defaults,
+			wantErr: "expands to more than 1048576 nodes",
+		},
+		{
+			name:   "small bomb within the limits",
+			raw:    yamlBomb(3),
+			limits: defaults,
+		},
+		{
+			name:    "node limit",
+			raw:     "a: [1, 2, 3, 4, 5]\n",
+			limits:  yamlLimits{maxDepth: 100, maxNodes: 5},
+			wantErr: "expands to more than 5 nodes",
+		},
+		{
+			name:    "depth limit",
+			raw:     "a: {b: {c: {d: 1}}}\n",
+			limits:  yamlLimits{maxDepth: 4, maxNodes: 100},
+			wantErr: "nested more than 4 levels deep",
+		},
+		{
+			name:    "alias to itself",
+			raw:     "a: &a [*a]\n",
+			limits:  defaults,
+			wantErr: "contains an alias to itself",
+		},
+		{
+			name:    "merge key referring to its own mapping",
+			raw:     "a: &a\n  b: 1\n  <<: *a\n",
+			limits:  defaults,
+			wantErr: "contains an alias to itself",
+		},
+		{
+			name:    "syntax error",
+			raw:     "a: [1, 2\n",
+			limits:  defaults,
+			wantErr: "did not find expected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unmarshalYAMLMap([]byte(tt.raw), tt.limits)
+			if len(tt.wantErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("unmarshalYAMLMap() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unmarshalYAMLMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithYAMLLimits(t *testing.T) {
+	o := newOptions([]Option{WithYAMLLimits(10, 0)})
+	if got, want := o.yamlLimits(), (yamlLimits{maxDepth: 10, maxNodes: defaultYAMLMaxNodes}); got != want {
+		t.Errorf("yamlLimits() = %+v, want %+v", got, want)
+	}
+}